@@ -65,7 +65,7 @@ func InitInputSourceWithContext(flags []cli.Flag, createInputSource func(context
 // ApplyInputSourceValue applies a generic value to the flagSet if required
 func (f *GenericFlag) ApplyInputSourceValue(context *cli.Context, isc InputSourceContext) error {
 	if f.set != nil {
-		if !context.IsSet(f.Name) && !isEnvVarSet(f.EnvVars) {
+		if !context.IsSet(f.Name) && !isEnvVarSet(f.EnvVars, f.DeprecatedEnvVars) {
 			value, err := isc.Generic(f.GenericFlag.Name)
 			if err != nil {
 				return err
@@ -84,7 +84,7 @@ func (f *GenericFlag) ApplyInputSourceValue(context *cli.Context, isc InputSourc
 // ApplyInputSourceValue applies a StringSlice value to the flagSet if required
 func (f *StringSliceFlag) ApplyInputSourceValue(context *cli.Context, isc InputSourceContext) error {
 	if f.set != nil {
-		if !context.IsSet(f.Name) && !isEnvVarSet(f.EnvVars) {
+		if !context.IsSet(f.Name) && !isEnvVarSet(f.EnvVars, f.DeprecatedEnvVars) {
 			value, err := isc.StringSlice(f.StringSliceFlag.Name)
 			if err != nil {
 				return err
@@ -106,7 +106,7 @@ func (f *StringSliceFlag) ApplyInputSourceValue(context *cli.Context, isc InputS
 // ApplyInputSourceValue applies a IntSlice value if required
 func (f *IntSliceFlag) ApplyInputSourceValue(context *cli.Context, isc InputSourceContext) error {
 	if f.set != nil {
-		if !context.IsSet(f.Name) && !isEnvVarSet(f.EnvVars) {
+		if !context.IsSet(f.Name) && !isEnvVarSet(f.EnvVars, f.DeprecatedEnvVars) {
 			value, err := isc.IntSlice(f.IntSliceFlag.Name)
 			if err != nil {
 				return err
@@ -128,7 +128,7 @@ func (f *IntSliceFlag) ApplyInputSourceValue(context *cli.Context, isc InputSour
 // ApplyInputSourceValue applies a Bool value to the flagSet if required
 func (f *BoolFlag) ApplyInputSourceValue(context *cli.Context, isc InputSourceContext) error {
 	if f.set != nil {
-		if !context.IsSet(f.Name) && !isEnvVarSet(f.EnvVars) {
+		if !context.IsSet(f.Name) && !isEnvVarSet(f.EnvVars, f.DeprecatedEnvVars) {
 			value, err := isc.Bool(f.BoolFlag.Name)
 			if err != nil {
 				return err
@@ -146,7 +146,7 @@ func (f *BoolFlag) ApplyInputSourceValue(context *cli.Context, isc InputSourceCo
 // ApplyInputSourceValue applies a String value to the flagSet if required
 func (f *StringFlag) ApplyInputSourceValue(context *cli.Context, isc InputSourceContext) error {
 	if f.set != nil {
-		if !(context.IsSet(f.Name) || isEnvVarSet(f.EnvVars)) {
+		if !(context.IsSet(f.Name) || isEnvVarSet(f.EnvVars, f.DeprecatedEnvVars)) {
 			value, err := isc.String(f.StringFlag.Name)
 			if err != nil {
 				return err
@@ -164,7 +164,7 @@ func (f *StringFlag) ApplyInputSourceValue(context *cli.Context, isc InputSource
 // ApplyInputSourceValue applies a Path value to the flagSet if required
 func (f *PathFlag) ApplyInputSourceValue(context *cli.Context, isc InputSourceContext) error {
 	if f.set != nil {
-		if !(context.IsSet(f.Name) || isEnvVarSet(f.EnvVars)) {
+		if !(context.IsSet(f.Name) || isEnvVarSet(f.EnvVars, f.DeprecatedEnvVars)) {
 			value, err := isc.String(f.PathFlag.Name)
 			if err != nil {
 				return err
@@ -192,7 +192,7 @@ func (f *PathFlag) ApplyInputSourceValue(context *cli.Context, isc InputSourceCo
 // ApplyInputSourceValue applies a int value to the flagSet if required
 func (f *IntFlag) ApplyInputSourceValue(context *cli.Context, isc InputSourceContext) error {
 	if f.set != nil {
-		if !(context.IsSet(f.Name) || isEnvVarSet(f.EnvVars)) {
+		if !(context.IsSet(f.Name) || isEnvVarSet(f.EnvVars, f.DeprecatedEnvVars)) {
 			value, err := isc.Int(f.IntFlag.Name)
 			if err != nil {
 				return err
@@ -210,7 +210,7 @@ func (f *IntFlag) ApplyInputSourceValue(context *cli.Context, isc InputSourceCon
 // ApplyInputSourceValue applies a Duration value to the flagSet if required
 func (f *DurationFlag) ApplyInputSourceValue(context *cli.Context, isc InputSourceContext) error {
 	if f.set != nil {
-		if !(context.IsSet(f.Name) || isEnvVarSet(f.EnvVars)) {
+		if !(context.IsSet(f.Name) || isEnvVarSet(f.EnvVars, f.DeprecatedEnvVars)) {
 			value, err := isc.Duration(f.DurationFlag.Name)
 			if err != nil {
 				return err
@@ -228,7 +228,7 @@ func (f *DurationFlag) ApplyInputSourceValue(context *cli.Context, isc InputSour
 // ApplyInputSourceValue applies a Float64 value to the flagSet if required
 func (f *Float64Flag) ApplyInputSourceValue(context *cli.Context, isc InputSourceContext) error {
 	if f.set != nil {
-		if !(context.IsSet(f.Name) || isEnvVarSet(f.EnvVars)) {
+		if !(context.IsSet(f.Name) || isEnvVarSet(f.EnvVars, f.DeprecatedEnvVars)) {
 			value, err := isc.Float64(f.Float64Flag.Name)
 			if err != nil {
 				return err
@@ -244,13 +244,15 @@ func (f *Float64Flag) ApplyInputSourceValue(context *cli.Context, isc InputSourc
 	return nil
 }
 
-func isEnvVarSet(envVars []string) bool {
-	for _, envVar := range envVars {
-		if _, ok := syscall.Getenv(envVar); ok {
-			// TODO: Can't use this for bools as
-			// set means that it was true or false based on
-			// Bool flag type, should work for other types
-			return true
+func isEnvVarSet(envVars []string, deprecatedEnvVars []string) bool {
+	for _, vars := range [][]string{envVars, deprecatedEnvVars} {
+		for _, envVar := range vars {
+			if _, ok := syscall.Getenv(envVar); ok {
+				// TODO: Can't use this for bools as
+				// set means that it was true or false based on
+				// Bool flag type, should work for other types
+				return true
+			}
 		}
 	}
 
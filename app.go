@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"time"
 )
@@ -43,11 +44,18 @@ type App struct {
 	Flags []Flag
 	// Boolean to enable bash completion commands
 	EnableBashCompletion bool
-	// Boolean to hide built-in help command and help flag
+	// Boolean to add the --print-command flag, which prints the resolved
+	// command to ErrWriter before it is run
+	EnablePrintCommand bool
+	// Boolean to hide built-in help command and help flag of the app itself.
+	// It is not inherited, commands still have help unless they hide it too.
 	HideHelp bool
 	// Boolean to hide built-in help command but keep help flag.
 	// Ignored if HideHelp is true.
 	HideHelpCommand bool
+	// Boolean to show help and exit with a non-zero code instead of running
+	// the Action when neither flags nor arguments are given
+	ShowHelpWhenEmpty bool
 	// Boolean to hide built-in version flag and the VERSION section of help
 	HideVersion bool
 	// categories contains the categorized commands and is populated on app startup
@@ -66,6 +74,20 @@ type App struct {
 	CommandNotFound CommandNotFoundFunc
 	// Execute this function if a usage error occurs
 	OnUsageError OnUsageErrorFunc
+	// Boolean to show the one-line UsageLine instead of the full help on
+	// usage errors
+	CompactUsageError bool
+	// Validates the arguments as paths in the file system, if set
+	FileArgs *FileArgs
+	// Validates the arguments against a set of allowed values, if set. Unless
+	// ArgsUsage is set, help shows the allowed values.
+	ChoiceArgs *ChoiceArgs
+	// Execute this function after parsing, but before checking required
+	// flags, to normalize flag values
+	NormalizeFlags NormalizeFlagsFunc
+	// Execute this function with the resolved flags right before the Action,
+	// if the app runs its own Action rather than a command's
+	OnResolved OnResolvedFunc
 	// Compilation date
 	Compiled time.Time
 	// List of all authors who contributed
@@ -86,6 +108,8 @@ type App struct {
 	Metadata map[string]interface{}
 	// Carries a function which returns app specific info.
 	ExtraInfo func() map[string]string
+	// Additional sections of the default help, e.g. ENVIRONMENT
+	HelpSections []HelpSection
 	// CustomAppHelpTemplate the text template for app help topic.
 	// cli.go uses text/template to render templates. You can
 	// render custom help text by setting this variable.
@@ -94,10 +118,70 @@ type App struct {
 	// single-character bool arguments into one
 	// i.e. foobar -o -v -> foobar -ov
 	UseShortOptionHandling bool
+	// Custom function to split combined short options when
+	// UseShortOptionHandling is enabled, i.e. to decide whether
+	// -abc means -ab -c or -a -b -c
+	ShortFlagResolver ShortFlagResolverFunc
+	// Boolean to make giving a flag which takes a single value more than
+	// once a usage error, rather than keeping the last value
+	ErrorOnDuplicateScalarFlag bool
+	// Time zone in which timestamp flags without their own Timezone parse
+	// values that do not specify one
+	DefaultTimezone *time.Location
+	// Execute this function with a summary of the invocation once the
+	// selected command has run
+	AuditFunc func(AuditRecord)
+	// Pattern of flag values to mask when printing the command and to leave
+	// out of exported environments, in addition to the values of sensitive
+	// flags, e.g. to avoid logging anything that looks like a token
+	RedactPattern *regexp.Regexp
+	// Execute this function to discover commands at runtime, in addition to
+	// Commands. It is called at most once per run, when a command is looked
+	// up for dispatch, help or completion.
+	DynamicCommands func(*Context) []*Command
+	// List of commands which are expensive to construct. They are listed in
+	// help and completion by their descriptions, a Factory is only called
+	// when its command is run, completed or shown by the help command.
+	LazyCommands []*LazyCommand
+	// What to do when the arguments do not name any of the commands
+	NoMatchBehavior NoMatchBehavior
+	// Boolean to return an error instead of printing a warning when flags
+	// are misconfigured, e.g. when a required flag has a default value
+	StrictFlagValidation bool
+	// File of KEY=VALUE lines to load into the environment before flags are
+	// read from it. Variables which are already set are not overridden.
+	EnvFile string
+	// Boolean to load ./.env like EnvFile, if it exists
+	AutoLoadDotEnv bool
 
 	didSetup bool
+
+	// names of the required flags which were declared with a default value,
+	// found before flags are applied as Apply stores env values in Value
+	requiredFlagDefaults []string
+
+	didDynamicCommands bool
+	dynamicCommands    []*Command
+
+	// the command whose subcommands this app runs, see Command.startApp
+	group *Command
 }
 
+// NoMatchBehavior defines what an App does when the first argument does not
+// name any of its commands
+type NoMatchBehavior int
+
+const (
+	// NoMatchRunAction runs the Action with the arguments, which shows help
+	// unless an Action is set. This is the default.
+	NoMatchRunAction NoMatchBehavior = iota
+	// NoMatchShowHelp shows help, even if an Action is set
+	NoMatchShowHelp
+	// NoMatchError returns an ExitCoder naming the unknown command, which
+	// exits with code 3
+	NoMatchError
+)
+
 // Tries to find out when this binary was compiled.
 // Returns the current time if it fails to find it.
 func compileTime() time.Time {
@@ -135,6 +219,10 @@ func (a *App) Setup() {
 
 	a.didSetup = true
 
+	for _, f := range requiredFlagsWithDefault(a.Flags, a.Commands) {
+		a.requiredFlagDefaults = append(a.requiredFlagDefaults, f.Names()[0])
+	}
+
 	if a.Name == "" {
 		a.Name = filepath.Base(os.Args[0])
 	}
@@ -175,6 +263,12 @@ func (a *App) Setup() {
 		a.ErrWriter = os.Stderr
 	}
 
+	if !a.StrictFlagValidation {
+		for _, name := range a.requiredFlagDefaults {
+			_, _ = fmt.Fprintf(a.ErrWriter, "warning: %s\n", requiredFlagDefaultError(name))
+		}
+	}
+
 	var newCommands []*Command
 
 	for _, c := range a.Commands {
@@ -199,6 +293,10 @@ func (a *App) Setup() {
 		a.appendFlag(VersionFlag)
 	}
 
+	if a.EnablePrintCommand && PrintCommandFlag != nil {
+		a.appendFlag(PrintCommandFlag)
+	}
+
 	a.categories = newCommandCategories()
 	for _, command := range a.Commands {
 		a.categories.AddCommand(command.Category, command)
@@ -211,13 +309,25 @@ func (a *App) Setup() {
 }
 
 func (a *App) newFlagSet() (*flag.FlagSet, error) {
-	return flagSet(a.Name, a.Flags)
+	return flagSet(a.Name, a.Flags, a.DefaultTimezone)
 }
 
 func (a *App) useShortOptionHandling() bool {
 	return a.UseShortOptionHandling
 }
 
+func (a *App) shortFlagResolver() ShortFlagResolverFunc {
+	return a.ShortFlagResolver
+}
+
+func (a *App) errorOnDuplicateScalarFlag() bool {
+	return a.ErrorOnDuplicateScalarFlag
+}
+
+func (a *App) flagsToParse() []Flag {
+	return a.Flags
+}
+
 // Run is the entry point to the cli app. Parses the arguments slice and routes
 // to the proper flag/args combination
 func (a *App) Run(arguments []string) (err error) {
@@ -228,7 +338,22 @@ func (a *App) Run(arguments []string) (err error) {
 // passed to its commands and sub-commands. Through this, you can
 // propagate timeouts and cancellation requests
 func (a *App) RunContext(ctx context.Context, arguments []string) (err error) {
+	return a.run(&Context{Context: ctx}, arguments)
+}
+
+// run runs the app like RunContext, in the mode of the parent context, e.g.
+// without Actions for RunValidateOnly or completing for CompletionJSON
+func (a *App) run(parent *Context, arguments []string) (err error) {
 	a.Setup()
+	a.resetDynamicCommands()
+
+	if err := a.checkRequiredFlagDefaults(); err != nil {
+		return err
+	}
+
+	if err := a.loadEnvFiles(); err != nil {
+		return err
+	}
 
 	// handle the completion flag separately from the flagset since
 	// completion could be attempted after a flag, but before its value was put
@@ -237,15 +362,22 @@ func (a *App) RunContext(ctx context.Context, arguments []string) (err error) {
 	// note that we can only do this because the shell autocomplete function
 	// always appends the completion flag at the end of the command
 	shellComplete, arguments := checkShellCompleteFlag(a, arguments)
+	if parent.completeFunc != nil {
+		// CompletionJSON completes without the completion flag
+		shellComplete = true
+	}
 
 	set, err := a.newFlagSet()
 	if err != nil {
 		return err
 	}
 
-	err = parseIter(set, a, arguments[1:], shellComplete)
+	occurrences, err := parseIter(set, a, arguments[1:], shellComplete)
 	nerr := normalizeFlags(a.Flags, set)
-	context := NewContext(a, set, &Context{Context: ctx})
+	context := NewContext(a, set, parent)
+	context.occurrences = occurrences
+	context.fromEnv = flagsFromEnv(a.Flags)
+	context.argsTerminated = argsTerminated(set, arguments[1:])
 	if nerr != nil {
 		_, _ = fmt.Fprintln(a.Writer, nerr)
 		_ = ShowAppHelp(context)
@@ -263,6 +395,10 @@ func (a *App) RunContext(ctx context.Context, arguments []string) (err error) {
 			a.handleExitCoder(context, err)
 			return err
 		}
+		if a.CompactUsageError {
+			printCompactUsageError(a.Writer, err, a.UsageLine())
+			return err
+		}
 		_, _ = fmt.Fprintf(a.Writer, "%s %s\n\n", "Incorrect Usage.", err.Error())
 		_ = ShowAppHelp(context)
 		return err
@@ -278,6 +414,26 @@ func (a *App) RunContext(ctx context.Context, arguments []string) (err error) {
 		return nil
 	}
 
+	if a.ShowHelpWhenEmpty && context.NArg() == 0 && context.NumFlags() == 0 {
+		_ = ShowAppHelp(context)
+		err := Exit("", 1)
+		a.handleExitCoder(context, err)
+		return err
+	}
+
+	if a.NormalizeFlags != nil {
+		if err := a.NormalizeFlags(context); err != nil {
+			a.handleExitCoder(context, err)
+			return err
+		}
+	}
+
+	context.warnDeprecatedEnvVars(a.Flags)
+
+	if err := context.checkTTYFlags(a.Flags); err != nil {
+		return err
+	}
+
 	cerr := context.checkRequiredFlags(a.Flags)
 	if cerr != nil {
 		_ = ShowAppHelp(context)
@@ -308,23 +464,90 @@ func (a *App) RunContext(ctx context.Context, arguments []string) (err error) {
 	args := context.Args()
 	if args.Present() {
 		name := args.First()
-		c := a.Command(name)
+		c := a.lookupCommand(context, name)
 		if c != nil {
 			return c.Run(context)
 		}
+		if handled, err := a.handleNoMatch(context, name, false); handled {
+			return err
+		}
+	}
+
+	if a.FileArgs != nil {
+		if err := a.FileArgs.validate(context.Args()); err != nil {
+			return err
+		}
+	}
+
+	if a.ChoiceArgs != nil {
+		if err := a.ChoiceArgs.validate(context.Args()); err != nil {
+			return err
+		}
+	}
+
+	if a.OnResolved != nil {
+		if err := a.OnResolved(context); err != nil {
+			a.handleExitCoder(context, err)
+			return err
+		}
+	}
+
+	if context.validateOnly {
+		return nil
 	}
 
 	if a.Action == nil {
 		a.Action = helpCommand.Action
 	}
 
+	context.printCommand()
+
 	// Run default Action
 	err = a.Action(context)
+	a.audit(context, a.Name)
 
 	a.handleExitCoder(context, err)
 	return err
 }
 
+// RunValidateOnly is like RunContext, but it skips the Action of the app and of
+// the command the arguments select. Flags are still parsed and checked, and the
+// Before, OnResolved and After functions are run, which is useful to validate
+// configuration without doing any work.
+func (a *App) RunValidateOnly(ctx context.Context, arguments []string) error {
+	return a.run(&Context{Context: ctx, validateOnly: true}, arguments)
+}
+
+// checkRequiredFlagDefaults returns an error under StrictFlagValidation for
+// the flags of the app and its commands which are required but also have a
+// default value, as the default makes the requirement moot. Otherwise Setup
+// warns about them once.
+func (a *App) checkRequiredFlagDefaults() error {
+	if a.StrictFlagValidation && len(a.requiredFlagDefaults) > 0 {
+		return requiredFlagDefaultError(a.requiredFlagDefaults[0])
+	}
+	return nil
+}
+
+func requiredFlagDefaultError(name string) error {
+	return fmt.Errorf("flag %q is required but has a default value", name)
+}
+
+func requiredFlagsWithDefault(flags []Flag, commands []*Command) []Flag {
+	var found []Flag
+	for _, f := range flags {
+		if rf, ok := f.(RequiredFlag); ok && rf.IsRequired() && len(f.Names()) > 0 && hasDefaultValue(f) {
+			found = append(found, f)
+		}
+	}
+
+	for _, c := range commands {
+		found = append(found, requiredFlagsWithDefault(c.Flags, c.Subcommands)...)
+	}
+
+	return found
+}
+
 // RunAndExitOnError calls .Run() and exits non-zero if an error was returned
 //
 // Deprecated: instead you should return an error that fulfills cli.ExitCoder
@@ -342,6 +565,7 @@ func (a *App) RunAndExitOnError() {
 func (a *App) RunAsSubcommand(ctx *Context) (err error) {
 	// Setup also handles HideHelp and HideHelpCommand
 	a.Setup()
+	a.resetDynamicCommands()
 
 	var newCmds []*Command
 	for _, c := range a.Commands {
@@ -357,9 +581,12 @@ func (a *App) RunAsSubcommand(ctx *Context) (err error) {
 		return err
 	}
 
-	err = parseIter(set, a, ctx.Args().Tail(), ctx.shellComplete)
+	occurrences, err := parseIter(set, a, ctx.Args().Tail(), ctx.shellComplete)
 	nerr := normalizeFlags(a.Flags, set)
 	context := NewContext(a, set, ctx)
+	context.occurrences = occurrences
+	context.fromEnv = flagsFromEnv(a.Flags)
+	context.argsTerminated = argsTerminated(set, ctx.Args().Tail())
 
 	if nerr != nil {
 		_, _ = fmt.Fprintln(a.Writer, nerr)
@@ -382,6 +609,10 @@ func (a *App) RunAsSubcommand(ctx *Context) (err error) {
 			a.handleExitCoder(context, err)
 			return err
 		}
+		if a.CompactUsageError {
+			printCompactUsageError(a.Writer, err, a.UsageLine())
+			return err
+		}
 		_, _ = fmt.Fprintf(a.Writer, "%s %s\n\n", "Incorrect Usage.", err.Error())
 		_ = ShowSubcommandHelp(context)
 		return err
@@ -397,6 +628,30 @@ func (a *App) RunAsSubcommand(ctx *Context) (err error) {
 		}
 	}
 
+	if a.group != nil {
+		context.warnDeprecatedCommand(a.group)
+	}
+
+	if a.ShowHelpWhenEmpty && context.NArg() == 0 && context.NumFlags() == 0 {
+		_ = ShowSubcommandHelp(context)
+		err := Exit("", 1)
+		a.handleExitCoder(context, err)
+		return err
+	}
+
+	if a.NormalizeFlags != nil {
+		if err := a.NormalizeFlags(context); err != nil {
+			a.handleExitCoder(context, err)
+			return err
+		}
+	}
+
+	context.warnDeprecatedEnvVars(a.Flags)
+
+	if err := context.checkTTYFlags(a.Flags); err != nil {
+		return err
+	}
+
 	cerr := context.checkRequiredFlags(a.Flags)
 	if cerr != nil {
 		_ = ShowSubcommandHelp(context)
@@ -429,19 +684,79 @@ func (a *App) RunAsSubcommand(ctx *Context) (err error) {
 	args := context.Args()
 	if args.Present() {
 		name := args.First()
-		c := a.Command(name)
+		c := a.lookupCommand(context, name)
 		if c != nil {
 			return c.Run(context)
 		}
+		if handled, err := a.handleNoMatch(context, name, true); handled {
+			return err
+		}
+	}
+
+	if a.FileArgs != nil {
+		if err := a.FileArgs.validate(context.Args()); err != nil {
+			return err
+		}
+	}
+
+	if a.ChoiceArgs != nil {
+		if err := a.ChoiceArgs.validate(context.Args()); err != nil {
+			return err
+		}
+	}
+
+	if a.OnResolved != nil {
+		if err := a.OnResolved(context); err != nil {
+			a.handleExitCoder(context, err)
+			return err
+		}
+	}
+
+	if context.validateOnly {
+		return nil
 	}
 
+	context.printCommand()
+
 	// Run default Action
 	err = a.Action(context)
+	a.audit(context, a.Name)
 
 	a.handleExitCoder(context, err)
 	return err
 }
 
+// Clone returns a deep copy of the app which can be run independently, e.g.
+// concurrently with the app itself. Flags are copied without the state of
+// earlier runs, Destinations are still shared, and so is the Value of a
+// GenericFlag, which can not be copied: give each clone its own Value to run
+// them concurrently.
+func (a *App) Clone() *App {
+	n := *a
+	n.Flags = cloneFlags(a.Flags)
+	n.Commands = cloneCommands(a.Commands)
+	n.didDynamicCommands = false
+	n.dynamicCommands = nil
+	n.LazyCommands = cloneLazyCommands(a.LazyCommands)
+
+	if a.Metadata != nil {
+		n.Metadata = make(map[string]interface{}, len(a.Metadata))
+		for k, v := range a.Metadata {
+			n.Metadata[k] = v
+		}
+	}
+
+	if a.didSetup {
+		n.categories = newCommandCategories()
+		for _, command := range n.Commands {
+			n.categories.AddCommand(command.Category, command)
+		}
+		sort.Sort(n.categories.(*commandCategories))
+	}
+
+	return &n
+}
+
 // Command returns the named command on App. Returns nil if the command does not exist
 func (a *App) Command(name string) *Command {
 	for _, c := range a.Commands {
@@ -453,11 +768,126 @@ func (a *App) Command(name string) *Command {
 	return nil
 }
 
+// lookupCommand returns the named command, including the ones from
+// LazyCommands and the ones discovered by DynamicCommands, or nil if it does
+// not exist
+func (a *App) lookupCommand(ctx *Context, name string) *Command {
+	if c := a.Command(name); c != nil {
+		return c
+	}
+
+	if c := a.lazyCommand(name); c != nil {
+		return c
+	}
+
+	for _, c := range a.discoverCommands(ctx) {
+		if c.HasName(name) {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// discoverCommands returns the commands from DynamicCommands, calling it only
+// once per run
+func (a *App) discoverCommands(ctx *Context) []*Command {
+	if a.DynamicCommands == nil || a.didDynamicCommands {
+		return a.dynamicCommands
+	}
+
+	a.didDynamicCommands = true
+	a.dynamicCommands = a.DynamicCommands(ctx)
+	for _, c := range a.dynamicCommands {
+		if c.HelpName == "" {
+			c.HelpName = fmt.Sprintf("%s %s", a.HelpName, c.Name)
+		}
+	}
+
+	return a.dynamicCommands
+}
+
+// lazyCommand returns the command from LazyCommands with the given name or
+// alias, or nil if there is none. Only the factory of that command is called.
+func (a *App) lazyCommand(name string) *Command {
+	for _, l := range a.LazyCommands {
+		if l.HasName(name) {
+			c := l.command()
+			if c != nil && c.HelpName == "" {
+				c.HelpName = fmt.Sprintf("%s %s", a.HelpName, c.Name)
+			}
+			return c
+		}
+	}
+	return nil
+}
+
+// lazyCommandListing returns the commands listing LazyCommands in help and
+// completion, without constructing them
+func (a *App) lazyCommandListing() []*Command {
+	var commands []*Command
+	for _, l := range a.LazyCommands {
+		commands = append(commands, l.listing())
+	}
+	return commands
+}
+
+func (a *App) resetDynamicCommands() {
+	a.didDynamicCommands = false
+	a.dynamicCommands = nil
+}
+
+// handleNoMatch applies the NoMatchBehavior when the first argument does not
+// name a command, returning false if the Action should run
+func (a *App) handleNoMatch(context *Context, name string, isSubcommand bool) (bool, error) {
+	if !a.hasCommands() {
+		return false, nil
+	}
+
+	switch a.NoMatchBehavior {
+	case NoMatchShowHelp:
+		if isSubcommand {
+			return true, ShowSubcommandHelp(context)
+		}
+		return true, ShowAppHelp(context)
+	case NoMatchError:
+		err := Exit(fmt.Sprintf("no command named %q", name), 3)
+		a.handleExitCoder(context, err)
+		return true, err
+	}
+
+	return false, nil
+}
+
+// hasCommands returns true if the app has commands besides the help command
+func (a *App) hasCommands() bool {
+	if a.DynamicCommands != nil || len(a.LazyCommands) > 0 {
+		return true
+	}
+
+	for _, c := range a.Commands {
+		if c != helpCommand && c != helpSubcommand {
+			return true
+		}
+	}
+
+	return false
+}
+
 // VisibleCategories returns a slice of categories and commands that are
 // Hidden=false
 func (a *App) VisibleCategories() []CommandCategory {
+	categories := a.categories
+	if len(a.LazyCommands) > 0 {
+		categories = newCommandCategories()
+		for _, command := range append(append([]*Command{}, a.Commands...), a.lazyCommandListing()...) {
+			categories.AddCommand(command.Category, command)
+		}
+		sort.Sort(categories.(*commandCategories))
+	}
+
 	ret := []CommandCategory{}
-	for _, category := range a.categories.Categories() {
+	for _, category := range categories.Categories() {
 		if visible := func() CommandCategory {
 			if len(category.VisibleCommands()) > 0 {
 				return category
@@ -473,7 +903,7 @@ func (a *App) VisibleCategories() []CommandCategory {
 // VisibleCommands returns a slice of the Commands with Hidden=false
 func (a *App) VisibleCommands() []*Command {
 	var ret []*Command
-	for _, command := range a.Commands {
+	for _, command := range append(append([]*Command{}, a.Commands...), a.lazyCommandListing()...) {
 		if !command.Hidden {
 			ret = append(ret, command)
 		}
@@ -481,6 +911,16 @@ func (a *App) VisibleCommands() []*Command {
 	return ret
 }
 
+// UsageLine returns a one-line synopsis of how to invoke the app, e.g.
+// "mytool [flags] <command>"
+func (a *App) UsageLine() string {
+	name := a.HelpName
+	if name == "" {
+		name = a.Name
+	}
+	return usageLine(name, len(a.VisibleFlags()) > 0, a.hasCommands(), a.ArgsUsage, a.ChoiceArgs)
+}
+
 // VisibleFlags returns a slice of the Flags with Hidden=false
 func (a *App) VisibleFlags() []Flag {
 	return visibleFlags(a.Flags)
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -490,6 +492,393 @@ func TestApp_RunAsSubCommandIncorrectUsage(t *testing.T) {
 	expect(t, err, errors.New("bad flag syntax: ---foo"))
 }
 
+func TestApp_DuplicateFlagNames(t *testing.T) {
+	a := &App{
+		Flags: []Flag{
+			&StringFlag{Name: "foo"},
+			&BoolFlag{Name: "bar", Aliases: []string{"foo"}},
+		},
+		Writer: ioutil.Discard,
+	}
+
+	err := a.Run([]string{"cmd", "--bar"})
+
+	expect(t, err, errors.New(`duplicate flag name "foo"`))
+}
+
+func TestApp_AuditFunc(t *testing.T) {
+	var records []AuditRecord
+	app := &App{
+		Name: "app",
+		Flags: []Flag{
+			&BoolFlag{Name: "verbose"},
+			&StringFlag{Name: "config"},
+		},
+		Commands: []*Command{
+			{
+				Name: "deploy",
+				Subcommands: []*Command{
+					{
+						Name: "prod",
+						Flags: []Flag{
+							&StringFlag{Name: "token", FlagOptions: FlagOptions{Sensitive: true}},
+							&StringFlag{Name: "region"},
+						},
+						Action: func(c *Context) error {
+							return nil
+						},
+					},
+				},
+			},
+		},
+		AuditFunc: func(record AuditRecord) {
+			records = append(records, record)
+		},
+		Writer: ioutil.Discard,
+	}
+
+	err := app.Run([]string{"app", "--verbose", "deploy", "prod", "--token", "s3cr3t", "--region", "eu", "a", "b"})
+
+	expect(t, err, nil)
+	expect(t, records, []AuditRecord{
+		{
+			Command: "app deploy prod",
+			Flags:   []string{"verbose", "token", "region"},
+			NArg:    2,
+		},
+	})
+}
+
+func TestApp_EnablePrintCommand(t *testing.T) {
+	errBuf := &bytes.Buffer{}
+	app := &App{
+		Name:               "app",
+		EnablePrintCommand: true,
+		Flags: []Flag{
+			&BoolFlag{Name: "verbose"},
+			&BoolFlag{Name: "color", Value: true},
+		},
+		Commands: []*Command{
+			{
+				Name: "deploy",
+				Subcommands: []*Command{
+					{
+						Name: "prod",
+						Flags: []Flag{
+							&StringFlag{Name: "region"},
+							&StringFlag{Name: "token", FlagOptions: FlagOptions{Sensitive: true}},
+							&IntFlag{Name: "n"},
+						},
+						Action: func(c *Context) error {
+							return nil
+						},
+					},
+				},
+			},
+		},
+		Writer:    ioutil.Discard,
+		ErrWriter: errBuf,
+	}
+
+	err := app.Run([]string{"app", "--print-command", "--verbose", "--color=false", "deploy", "prod", "-n", "3", "--token", "s3cr3t", "--region", "eu west", "a", "b"})
+	expect(t, err, nil)
+	expect(t, errBuf.String(), "app --verbose --color=false deploy prod --region='eu west' --token=***** -n=3 a b\n")
+
+	errBuf.Reset()
+	err = app.Run([]string{"app", "--print-command", "deploy", "prod", "--region", "it's", "$HOME"})
+	expect(t, err, nil)
+	expect(t, errBuf.String(), "app deploy prod --region='it'\\''s' '$HOME'\n")
+
+	errBuf.Reset()
+	err = app.Run([]string{"app", "deploy", "prod", "a"})
+	expect(t, err, nil)
+	expect(t, errBuf.String(), "")
+}
+
+func TestCommand_EnablePrintCommand(t *testing.T) {
+	errBuf := &bytes.Buffer{}
+	app := &App{
+		Name: "app",
+		Commands: []*Command{
+			{
+				Name:               "deploy",
+				EnablePrintCommand: true,
+				Flags:              []Flag{&StringFlag{Name: "region"}},
+				Action: func(c *Context) error {
+					return nil
+				},
+			},
+			{
+				Name: "status",
+				Action: func(c *Context) error {
+					return nil
+				},
+			},
+		},
+		Writer:    ioutil.Discard,
+		ErrWriter: errBuf,
+	}
+
+	err := app.Run([]string{"app", "deploy", "--print-command", "--region", "eu", "a"})
+	expect(t, err, nil)
+	expect(t, errBuf.String(), "app deploy --region=eu a\n")
+
+	err = app.Run([]string{"app", "status", "--print-command"})
+	expect(t, err, errors.New("flag provided but not defined: -print-command"))
+}
+
+func TestApp_DynamicCommands(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = []string{"app", "--generate-bash-completion"}
+
+	var discovered, ran int
+	buf := &bytes.Buffer{}
+	app := &App{
+		Name:                 "app",
+		EnableBashCompletion: true,
+		Commands: []*Command{
+			{Name: "static"},
+		},
+		DynamicCommands: func(c *Context) []*Command {
+			discovered++
+			return []*Command{
+				{
+					Name: "plugin",
+					Action: func(c *Context) error {
+						ran++
+						return nil
+					},
+				},
+			}
+		},
+		Writer: buf,
+	}
+
+	err := app.Run(os.Args)
+
+	expect(t, err, nil)
+	expect(t, discovered, 1)
+	expect(t, buf.String(), "static\nhelp\nh\nplugin\n")
+
+	err = app.Run([]string{"app", "plugin"})
+
+	expect(t, err, nil)
+	expect(t, discovered, 2)
+	expect(t, ran, 1)
+}
+
+func TestApp_DynamicSubcommands(t *testing.T) {
+	var discovered int
+	var ran string
+	app := &App{
+		Name: "app",
+		Commands: []*Command{
+			{
+				Name: "plugins",
+				DynamicCommands: func(c *Context) []*Command {
+					discovered++
+					return []*Command{
+						{
+							Name: "greet",
+							Action: func(c *Context) error {
+								ran = c.Args().First()
+								return nil
+							},
+						},
+					}
+				},
+			},
+		},
+		Writer: ioutil.Discard,
+	}
+
+	err := app.Run([]string{"app", "plugins", "greet", "world"})
+
+	expect(t, err, nil)
+	expect(t, discovered, 1)
+	expect(t, ran, "world")
+}
+
+func TestApp_LazyCommands(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+
+	var constructed, constructedLeaf, ran int
+	buf := &bytes.Buffer{}
+	app := &App{
+		Name: "app",
+		Commands: []*Command{
+			{Name: "static", Action: func(c *Context) error { return nil }},
+			{
+				Name: "grp",
+				LazyCommands: []*LazyCommand{
+					{
+						Name:  "leaf",
+						Usage: "a lazy leaf",
+						Factory: func() *Command {
+							constructedLeaf++
+							return &Command{
+								Name:   "leaf",
+								Usage:  "a lazy leaf",
+								Action: func(c *Context) error { return nil },
+							}
+						},
+					},
+				},
+			},
+		},
+		LazyCommands: []*LazyCommand{
+			{
+				Name:    "heavy",
+				Aliases: []string{"hv"},
+				Usage:   "do expensive things",
+				Factory: func() *Command {
+					constructed++
+					return &Command{
+						Name:    "heavy",
+						Aliases: []string{"hv"},
+						Usage:   "do expensive things",
+						Action: func(c *Context) error {
+							ran++
+							return nil
+						},
+					}
+				},
+			},
+		},
+		EnableBashCompletion: true,
+		Writer:               buf,
+	}
+
+	err := app.Run([]string{"app", "static"})
+	expect(t, err, nil)
+	expect(t, constructed, 0)
+
+	_ = app.Run([]string{"app", "zzz"})
+	expect(t, constructed, 0)
+
+	buf.Reset()
+	err = app.Run([]string{"app", "--help"})
+	expect(t, err, nil)
+	expect(t, constructed, 0)
+	if !strings.Contains(buf.String(), "heavy, hv  do expensive things") {
+		t.Errorf("expected the lazy command in the app help, got: %q", buf.String())
+	}
+
+	buf.Reset()
+	os.Args = []string{"app", "--generate-bash-completion"}
+	err = app.Run(os.Args)
+	expect(t, err, nil)
+	expect(t, constructed, 0)
+	expect(t, buf.String(), "static\ngrp\nhelp\nh\nheavy\nhv\n")
+
+	err = app.Run([]string{"app", "hv"})
+	expect(t, err, nil)
+	expect(t, constructed, 1)
+	expect(t, ran, 1)
+
+	err = app.Run([]string{"app", "heavy"})
+	expect(t, err, nil)
+	expect(t, constructed, 1)
+	expect(t, ran, 2)
+
+	buf.Reset()
+	err = app.Run([]string{"app", "help", "heavy"})
+	expect(t, err, nil)
+	expect(t, constructed, 1)
+	if !strings.Contains(buf.String(), "app heavy - do expensive things") {
+		t.Errorf("expected help for the lazy command, got: %q", buf.String())
+	}
+
+	buf.Reset()
+	err = app.Run([]string{"app", "grp", "--help"})
+	expect(t, err, nil)
+	expect(t, constructedLeaf, 0)
+	if !strings.Contains(buf.String(), "leaf     a lazy leaf") {
+		t.Errorf("expected the lazy command in the group help, got: %q", buf.String())
+	}
+
+	// nested factories are called once, although the app running them is
+	// created for each run
+	for i := 0; i < 2; i++ {
+		err = app.Run([]string{"app", "grp", "leaf"})
+		expect(t, err, nil)
+	}
+	expect(t, constructedLeaf, 1)
+}
+
+func TestApp_NoMatchBehavior(t *testing.T) {
+	cases := []struct {
+		behavior NoMatchBehavior
+		ran      bool
+		help     bool
+		err      error
+	}{
+		{behavior: NoMatchRunAction, ran: true},
+		{behavior: NoMatchShowHelp, help: true},
+		{behavior: NoMatchError, err: Exit(`no command named "bar"`, 3)},
+	}
+
+	for _, c := range cases {
+		var ran, subRan bool
+		lastExitCode = 0
+		buf := &bytes.Buffer{}
+		app := &App{
+			Name:            "app",
+			NoMatchBehavior: c.behavior,
+			Commands: []*Command{
+				{Name: "foo"},
+				{
+					Name:            "sub",
+					NoMatchBehavior: c.behavior,
+					Subcommands:     []*Command{{Name: "baz"}},
+					Action: func(c *Context) error {
+						subRan = true
+						return nil
+					},
+				},
+			},
+			Action: func(c *Context) error {
+				ran = true
+				return nil
+			},
+			Writer: buf,
+		}
+
+		err := app.Run([]string{"app", "bar"})
+
+		expect(t, err, c.err)
+		expect(t, ran, c.ran)
+		expect(t, strings.Contains(buf.String(), "COMMANDS:"), c.help)
+
+		buf.Reset()
+		err = app.Run([]string{"app", "sub", "bar"})
+
+		expect(t, err, c.err)
+		expect(t, subRan, c.ran)
+		expect(t, strings.Contains(buf.String(), "COMMANDS:"), c.help)
+		if c.err != nil {
+			expect(t, lastExitCode, 3)
+		}
+	}
+}
+
+func TestApp_NoMatchBehaviorWithoutCommands(t *testing.T) {
+	var args []string
+	app := &App{
+		NoMatchBehavior: NoMatchError,
+		Action: func(c *Context) error {
+			args = c.Args().Slice()
+			return nil
+		},
+		Writer: ioutil.Discard,
+	}
+
+	err := app.Run([]string{"app", "bar"})
+
+	expect(t, err, nil)
+	expect(t, args, []string{"bar"})
+}
+
 func TestApp_CommandWithFlagBeforeTerminator(t *testing.T) {
 	var parsedOption string
 	var args Args
@@ -1039,6 +1428,206 @@ func TestApp_BeforeFunc(t *testing.T) {
 	}
 }
 
+func TestApp_RequiredFlagWithDefault(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		errBuf := &bytes.Buffer{}
+		app := &App{
+			Flags: []Flag{
+				&StringFlag{Name: "opt", Required: true},
+				&IntSliceFlag{Name: "empty", Required: true, Value: NewIntSlice()},
+			},
+			Commands: []*Command{
+				{
+					Name: "sub",
+					Flags: []Flag{
+						&StringFlag{Name: "level", Required: true, Value: "info"},
+						&StringFlag{Name: "name", Value: "me"},
+					},
+				},
+			},
+			Action:               func(c *Context) error { return nil },
+			StrictFlagValidation: strict,
+			Writer:               ioutil.Discard,
+			ErrWriter:            errBuf,
+		}
+
+		// the warning is written once, not on every run
+		for i := 0; i < 2; i++ {
+			err := app.Run([]string{"command", "--opt", "foo", "--empty", "1"})
+
+			if strict {
+				expect(t, err, errors.New(`flag "level" is required but has a default value`))
+				expect(t, errBuf.String(), "")
+			} else {
+				expect(t, err, nil)
+				expect(t, errBuf.String(), "warning: flag \"level\" is required but has a default value\n")
+			}
+		}
+	}
+}
+
+func TestApp_RequiredFlagSetFromEnv_ReusedApp(t *testing.T) {
+	defer resetEnv(os.Environ())
+	os.Clearenv()
+
+	app := &App{
+		Flags: []Flag{
+			&StringFlag{Name: "token", Required: true, EnvVars: []string{"APP_TOKEN"}},
+		},
+		Action:               func(c *Context) error { return nil },
+		StrictFlagValidation: true,
+		Writer:               ioutil.Discard,
+	}
+
+	_ = os.Setenv("APP_TOKEN", "s3cr3t")
+	expect(t, app.Run([]string{"command"}), nil)
+	expect(t, app.Run([]string{"command"}), nil)
+
+	_ = os.Unsetenv("APP_TOKEN")
+	expect(t, app.Run([]string{"command", "--token", "t"}), nil)
+}
+
+func TestApp_RunValidateOnly(t *testing.T) {
+	var before, resolved, after, action, subAction bool
+	app := &App{
+		Flags:      []Flag{&StringFlag{Name: "config", Required: true}},
+		Before:     func(c *Context) error { before = true; return nil },
+		OnResolved: func(c *Context) error { resolved = true; return nil },
+		After:      func(c *Context) error { after = true; return nil },
+		Action:     func(c *Context) error { action = true; return nil },
+		Commands: []*Command{
+			{
+				Name:   "deploy",
+				Action: func(c *Context) error { subAction = true; return nil },
+			},
+		},
+		Writer: ioutil.Discard,
+	}
+
+	err := app.RunValidateOnly(context.Background(), []string{"command", "--config", "a.yml"})
+	expect(t, err, nil)
+	expect(t, before, true)
+	expect(t, resolved, true)
+	expect(t, after, true)
+	expect(t, action, false)
+
+	err = app.RunValidateOnly(context.Background(), []string{"command", "--config", "a.yml", "deploy"})
+	expect(t, err, nil)
+	expect(t, subAction, false)
+
+	err = app.RunValidateOnly(context.Background(), []string{"command"})
+	if err == nil {
+		t.Error("expected the missing required flag to be reported")
+	}
+
+	err = app.Run([]string{"command", "--config", "a.yml", "deploy"})
+	expect(t, err, nil)
+	expect(t, subAction, true)
+}
+
+func TestApp_EnvFile(t *testing.T) {
+	defer resetEnv(os.Environ())
+	os.Clearenv()
+	_ = os.Setenv("APP_REGION", "eu")
+
+	f, err := ioutil.TempFile("", "urfave_cli_env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, _ = f.WriteString(`# settings
+APP_NAME="my app" # quoted
+export APP_TOKEN='s3cr#t'
+APP_LEVEL=debug # trailing comment
+
+APP_REGION=us
+APP_GREETING="say \"hi\"" # a "quoted" comment
+APP_PATH="C:\dir\\x\tmp"
+APP_MODE='fast' # 'quick' mode
+APP_LINES="a\nb"
+`)
+	_ = f.Close()
+
+	var name, token, level, region string
+	app := &App{
+		EnvFile: f.Name(),
+		Flags: []Flag{
+			&StringFlag{Name: "name", EnvVars: []string{"APP_NAME"}, Destination: &name},
+			&StringFlag{Name: "token", EnvVars: []string{"APP_TOKEN"}, Destination: &token},
+			&StringFlag{Name: "level", EnvVars: []string{"APP_LEVEL"}, Destination: &level},
+			&StringFlag{Name: "region", EnvVars: []string{"APP_REGION"}, Destination: &region},
+		},
+		Action: func(c *Context) error { return nil },
+	}
+
+	err = app.Run([]string{"command"})
+	expect(t, err, nil)
+	expect(t, name, "my app")
+	expect(t, token, "s3cr#t")
+	expect(t, level, "debug")
+	expect(t, region, "eu")
+	expect(t, os.Getenv("APP_GREETING"), `say "hi"`)
+	expect(t, os.Getenv("APP_PATH"), `C:\dir\x\tmp`)
+	expect(t, os.Getenv("APP_MODE"), "fast")
+	expect(t, os.Getenv("APP_LINES"), "a\nb")
+
+	app.EnvFile = f.Name() + ".missing"
+	if err := app.Run([]string{"command"}); err == nil {
+		t.Error("expected an error for a missing env file")
+	}
+}
+
+func TestApp_Clone(t *testing.T) {
+	app := &App{
+		Writer: ioutil.Discard,
+		Commands: []*Command{
+			{
+				Name: "greet",
+				Flags: []Flag{
+					&StringFlag{Name: "name", EnvVars: []string{"APP_CLONE_NAME"}},
+					&StringSliceFlag{Name: "tag", Value: NewStringSlice("default")},
+				},
+				Before: func(c *Context) error {
+					c.Command.Usage = "greeted " + c.String("name")
+					return nil
+				},
+				Action: func(c *Context) error {
+					if c.String("name") == "" {
+						return errors.New("no name")
+					}
+					return nil
+				},
+			},
+		},
+	}
+	_ = app.Run([]string{"app", "greet", "--name", "setup"})
+
+	var wg sync.WaitGroup
+	clones := make([]*App, 10)
+	errs := make([]error, len(clones))
+	for i := range clones {
+		clones[i] = app.Clone()
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = clones[i].Run([]string{"app", "greet", "--name", fmt.Sprintf("n%d", i), "--tag", "t"})
+		}(i)
+	}
+	wg.Wait()
+
+	for i, clone := range clones {
+		expect(t, errs[i], nil)
+		expect(t, clone.Commands[0].Usage, fmt.Sprintf("greeted n%d", i))
+	}
+	expect(t, app.Commands[0].Usage, "greeted setup")
+	expect(t, app.Commands[0].Flags[1].(*StringSliceFlag).Value.Value(), []string{"default"})
+
+	clone := app.Clone()
+	if clone.Commands[0] == app.Commands[0] || clone.Commands[0].Flags[0] == app.Commands[0].Flags[0] {
+		t.Error("expected commands and flags to be copied")
+	}
+}
+
 func TestApp_AfterFunc(t *testing.T) {
 	counts := &opCounts{}
 	afterError := fmt.Errorf("fail")
@@ -2084,7 +2673,7 @@ func TestCustomHelpVersionFlags(t *testing.T) {
 
 func TestHandleExitCoder_Default(t *testing.T) {
 	app := newTestApp()
-	fs, err := flagSet(app.Name, app.Flags)
+	fs, err := flagSet(app.Name, app.Flags, nil)
 	if err != nil {
 		t.Errorf("error creating FlagSet: %s", err)
 	}
@@ -2100,7 +2689,7 @@ func TestHandleExitCoder_Default(t *testing.T) {
 
 func TestHandleExitCoder_Custom(t *testing.T) {
 	app := newTestApp()
-	fs, err := flagSet(app.Name, app.Flags)
+	fs, err := flagSet(app.Name, app.Flags, nil)
 	if err != nil {
 		t.Errorf("error creating FlagSet: %s", err)
 	}
@@ -2253,3 +2842,53 @@ func TestSetupInitializesOnlyNilWriters(t *testing.T) {
 		t.Errorf("expected a.Writer to be os.Stdout")
 	}
 }
+
+func TestApp_RootFlagsBeforeSubcommand(t *testing.T) {
+	for _, shortOptions := range []bool{false, true} {
+		var dir, sub string
+		var verbose, force bool
+		app := &App{
+			UseShortOptionHandling: shortOptions,
+			Flags: []Flag{
+				&StringFlag{Name: "root-flag", Aliases: []string{"C"}},
+				&BoolFlag{Name: "verbose", Aliases: []string{"v"}},
+			},
+			Commands: []*Command{
+				{
+					Name: "subcmd",
+					Flags: []Flag{
+						&BoolFlag{Name: "sub-flag", Aliases: []string{"f"}},
+						&StringFlag{Name: "name"},
+					},
+					Action: func(c *Context) error {
+						dir = c.String("root-flag")
+						verbose = c.Bool("verbose")
+						force = c.Bool("sub-flag")
+						sub = c.String("name")
+						return nil
+					},
+				},
+			},
+		}
+
+		err := app.Run([]string{"mytool", "--root-flag", "val", "subcmd", "--sub-flag"})
+		expect(t, err, nil)
+		expect(t, dir, "val")
+		expect(t, force, true)
+
+		err = app.Run([]string{"mytool", "-v", "-C", "/path", "subcmd", "--name", "x", "-f"})
+		expect(t, err, nil)
+		expect(t, dir, "/path")
+		expect(t, verbose, true)
+		expect(t, sub, "x")
+		expect(t, force, true)
+
+		if shortOptions {
+			verbose = false
+			err = app.Run([]string{"mytool", "-vC", "/other", "subcmd"})
+			expect(t, err, nil)
+			expect(t, dir, "/other")
+			expect(t, verbose, true)
+		}
+	}
+}
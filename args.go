@@ -1,5 +1,11 @@
 package cli
 
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
 type Args interface {
 	// Get returns the nth argument, or else a blank string
 	Get(n int) string
@@ -52,3 +58,114 @@ func (a *args) Slice() []string {
 	copy(ret, *a)
 	return ret
 }
+
+// FileArgsMode defines which kind of path FileArgs accepts
+type FileArgsMode int
+
+const (
+	// FileArgsAny accepts files and directories
+	FileArgsAny FileArgsMode = iota
+	// FileArgsFile accepts files only
+	FileArgsFile
+	// FileArgsDir accepts directories only
+	FileArgsDir
+)
+
+// FileArgs validates that the arguments are paths which exist in the file
+// system before the Action is run
+type FileArgs struct {
+	// Min is the minimum number of arguments
+	Min int
+	// Max is the maximum number of arguments, or 0 for no limit
+	Max int
+	// Mode restricts the kind of the paths
+	Mode FileArgsMode
+	// Readable requires files to be readable
+	Readable bool
+}
+
+func (fa *FileArgs) validate(args Args) error {
+	if args.Len() < fa.Min {
+		return fmt.Errorf("expected at least %d arguments, got %d", fa.Min, args.Len())
+	}
+	if fa.Max > 0 && args.Len() > fa.Max {
+		return fmt.Errorf("expected at most %d arguments, got %d", fa.Max, args.Len())
+	}
+
+	for i, path := range args.Slice() {
+		fi, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("argument %d: file not found: %s", i+1, path)
+			}
+			return fmt.Errorf("argument %d: %s", i+1, err)
+		}
+
+		switch {
+		case fa.Mode == FileArgsFile && fi.IsDir():
+			return fmt.Errorf("argument %d: is a directory: %s", i+1, path)
+		case fa.Mode == FileArgsDir && !fi.IsDir():
+			return fmt.Errorf("argument %d: not a directory: %s", i+1, path)
+		}
+
+		if fa.Readable && !fi.IsDir() {
+			f, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("argument %d: file not readable: %s", i+1, path)
+			}
+			_ = f.Close()
+		}
+	}
+
+	return nil
+}
+
+// ChoiceArgs validates that the arguments are among a set of allowed values
+// before the Action is run, e.g. for "mytool scale <up|down>"
+type ChoiceArgs struct {
+	// AllowedValues are the values the arguments may take
+	AllowedValues []string
+	// Min is the minimum number of arguments
+	Min int
+	// Max is the maximum number of arguments, or 0 for no limit
+	Max int
+}
+
+// Usage returns how the arguments are shown in help, e.g. "<up|down>" for a
+// single required argument or "[up|down...]" for any number of them
+func (ca *ChoiceArgs) Usage() string {
+	usage := strings.Join(ca.AllowedValues, "|")
+	if ca.Max != 1 {
+		usage += "..."
+	}
+	if ca.Min > 0 {
+		return "<" + usage + ">"
+	}
+	return "[" + usage + "]"
+}
+
+func (ca *ChoiceArgs) validate(args Args) error {
+	if args.Len() < ca.Min {
+		return fmt.Errorf("expected at least %d arguments, got %d", ca.Min, args.Len())
+	}
+	if ca.Max > 0 && args.Len() > ca.Max {
+		return fmt.Errorf("expected at most %d arguments, got %d", ca.Max, args.Len())
+	}
+
+	for i, arg := range args.Slice() {
+		if !ca.allows(arg) {
+			return fmt.Errorf("argument %d: invalid value %q, must be one of: %s", i+1, arg, strings.Join(ca.AllowedValues, ", "))
+		}
+	}
+
+	return nil
+}
+
+func (ca *ChoiceArgs) allows(arg string) bool {
+	for _, value := range ca.AllowedValues {
+		if value == arg {
+			return true
+		}
+	}
+	return false
+}
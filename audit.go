@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// sensitiveMask replaces the value of sensitive flags in the output of the
+// PrintCommandFlag
+const sensitiveMask = "*****"
+
+// AuditRecord summarizes an invocation of an App. It is passed to the
+// AuditFunc of the App once the selected command has run.
+type AuditRecord struct {
+	// Full name of the command that was run, including the app name
+	Command string
+	// Names of the flags that were set, from the root command down
+	Flags []string
+	// Number of positional arguments given to the command
+	NArg int
+}
+
+func (a *App) audit(context *Context, command string) {
+	if a.AuditFunc == nil {
+		return
+	}
+
+	record := AuditRecord{
+		Command: command,
+		Flags:   []string{},
+		NArg:    context.NArg(),
+	}
+
+	for _, f := range context.setFlags() {
+		record.Flags = append(record.Flags, f.name)
+	}
+
+	a.AuditFunc(record)
+}
+
+// maskedValue returns the value of a set flag, or sensitiveMask if the flag is
+// sensitive or the value matches the RedactPattern of the app
+func (c *Context) maskedValue(f setFlag) string {
+	if isSensitive(f.flag) || redacts(c.redactPattern(), f.value) {
+		return sensitiveMask
+	}
+	return f.value
+}
+
+func (c *Context) redactPattern() *regexp.Regexp {
+	for _, ctx := range c.Lineage() {
+		if ctx.App != nil && ctx.App.RedactPattern != nil {
+			return ctx.App.RedactPattern
+		}
+	}
+	return nil
+}
+
+func redacts(pattern *regexp.Regexp, value string) bool {
+	return pattern != nil && pattern.MatchString(value)
+}
+
+// printCommand writes the resolved command to the ErrWriter if the
+// PrintCommandFlag was given, e.g. "app deploy --region=eu target". Flags are
+// given after the command they belong to, and the values of sensitive flags
+// and values matching the RedactPattern of the app are masked.
+func (c *Context) printCommand() {
+	if PrintCommandFlag == nil || !c.Bool(PrintCommandFlag.Names()[0]) {
+		return
+	}
+
+	var words []string
+	var name string
+	flags := c.setFlags()
+	lineage := c.Lineage()
+	for i := len(lineage) - 1; i >= 0; i-- {
+		ctx := lineage[i]
+		if ctx.App == nil {
+			continue
+		}
+
+		// the names of subcommand apps include the names of their parents
+		fullName := ctx.App.Name
+		if ctx.Command != nil && ctx.Command.Name != "" {
+			fullName += " " + ctx.Command.Name
+		}
+		if fullName != name {
+			words = append(words, strings.Fields(strings.TrimPrefix(fullName, name))...)
+			name = fullName
+		}
+
+		for _, f := range flags {
+			if f.ctx != ctx || f.flag == PrintCommandFlag {
+				continue
+			}
+			arg := prefixFor(f.name) + f.name
+			if value := c.maskedValue(f); value == sensitiveMask {
+				arg += "=" + value
+			} else if flagTakesValue(f.flag) || value != "true" {
+				arg += "=" + quoteArg(value)
+			}
+			words = append(words, arg)
+		}
+	}
+
+	if c.ArgsTerminated() {
+		words = append(words, "--")
+	}
+	for _, arg := range c.Args().Slice() {
+		words = append(words, quoteArg(arg))
+	}
+
+	_, _ = fmt.Fprintln(c.ErrorWriter(), strings.Join(words, " "))
+}
+
+// quoteArg single quotes an argument which is empty or contains spaces or
+// characters special to shells, so that shells take it as is
+func quoteArg(arg string) string {
+	if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$`;&|<>*?()") {
+		return "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+	}
+	return arg
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// ToBashCompletion creates a bash completion script for the `*App`, which
+// completes the program under the name of the app, or under the name it was
+// invoked as if the app has none, e.g. for a symlinked multi-call binary.
+// The function errors if either parsing or writing of the script fails.
+func (a *App) ToBashCompletion() (string, error) {
+	return a.completionScript(BashCompletionTemplate)
+}
+
+// ToZshCompletion creates a zsh completion script for the `*App`, named like
+// the one of ToBashCompletion.
+// The function errors if either parsing or writing of the script fails.
+func (a *App) ToZshCompletion() (string, error) {
+	return a.completionScript(ZshCompletionTemplate)
+}
+
+type completionScriptTemplate struct {
+	Name     string
+	FuncName string
+	// whether the script completes the program named by $PROG, like the
+	// scripts in the autocomplete directory
+	Generic bool
+}
+
+func (a *App) completionScript(text string) (string, error) {
+	name := a.Name
+	if name == "" {
+		name = filepath.Base(os.Args[0])
+	}
+
+	return renderCompletionScript(text, &completionScriptTemplate{
+		Name:     name,
+		FuncName: bashFuncName(name),
+	})
+}
+
+func renderCompletionScript(text string, data *completionScriptTemplate) (string, error) {
+	t, err := template.New("cli").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var w bytes.Buffer
+	if err := t.Execute(&w, data); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
+
+// bashFuncName turns a program name into one usable in a bash function name
+func bashFuncName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBashCompletion(t *testing.T) {
+	app := &App{Name: "my-tool"}
+
+	res, err := app.ToBashCompletion()
+	expect(t, err, nil)
+
+	if !strings.Contains(res, "\ncomplete -o bashdefault -o default -o nospace -F _my_tool_bash_autocomplete my-tool\n") {
+		t.Errorf("expected completion for the program name, got:\n%s", res)
+	}
+}
+
+func TestBashCompletion_InvokedName(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = []string{"/usr/local/bin/ls"}
+
+	res, err := (&App{}).ToBashCompletion()
+	expect(t, err, nil)
+
+	if !strings.Contains(res, "-F _ls_bash_autocomplete ls\n") {
+		t.Errorf("expected completion for the invoked name, got:\n%s", res)
+	}
+}
+
+func TestBashCompletion_NoPrologue(t *testing.T) {
+	res, err := (&App{Name: "my-tool"}).ToBashCompletion()
+	expect(t, err, nil)
+
+	if !strings.HasPrefix(res, "#! /bin/bash\n# my-tool bash completion\n\n_my_tool_bash_autocomplete() {\n") ||
+		strings.Contains(res, "PROG") {
+		t.Errorf("expected a script for the program name only, got:\n%s", res)
+	}
+}
+
+func TestZshCompletion(t *testing.T) {
+	res, err := (&App{Name: "my-tool"}).ToZshCompletion()
+	expect(t, err, nil)
+
+	if !strings.HasPrefix(res, "#compdef my-tool\n") ||
+		!strings.HasSuffix(res, "\ncompdef _my_tool_zsh_autocomplete my-tool\n") {
+		t.Errorf("expected completion for the program name, got:\n%s", res)
+	}
+}
+
+// genericCompletionScript renders the script shipped in the autocomplete
+// directory, which completes the program named by $PROG
+func genericCompletionScript(text string) (string, error) {
+	return renderCompletionScript(text, &completionScriptTemplate{
+		Name:     "$PROG",
+		FuncName: "cli",
+		Generic:  true,
+	})
+}
+
+func TestCompletionScripts_Shipped(t *testing.T) {
+	res, err := genericCompletionScript(BashCompletionTemplate)
+	expect(t, err, nil)
+	expectFileContent(t, "autocomplete/bash_autocomplete", res)
+
+	res, err = genericCompletionScript(ZshCompletionTemplate)
+	expect(t, err, nil)
+	expectFileContent(t, "autocomplete/zsh_autocomplete", res)
+}
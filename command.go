@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 )
 
 // Command is a subcommand for a cli.App.
@@ -21,6 +22,11 @@ type Command struct {
 	Description string
 	// A short description of the arguments of this command
 	ArgsUsage string
+	// Validates the arguments as paths in the file system, if set
+	FileArgs *FileArgs
+	// Validates the arguments against a set of allowed values, if set. Unless
+	// ArgsUsage is set, help shows the allowed values.
+	ChoiceArgs *ChoiceArgs
 	// The category the command is part of
 	Category string
 	// The function to call when checking for bash command completions
@@ -35,8 +41,25 @@ type Command struct {
 	Action ActionFunc
 	// Execute this function if a usage error occurs.
 	OnUsageError OnUsageErrorFunc
+	// Boolean to show the one-line UsageLine instead of the full help on
+	// usage errors
+	CompactUsageError bool
+	// Execute this function after parsing, but before checking required
+	// flags, to normalize flag values
+	NormalizeFlags NormalizeFlagsFunc
+	// Execute this function with the resolved flags right before the Action,
+	// if this command runs its own Action rather than a subcommand's
+	OnResolved OnResolvedFunc
 	// List of child commands
 	Subcommands []*Command
+	// Execute this function to discover child commands at runtime, in
+	// addition to Subcommands
+	DynamicCommands func(*Context) []*Command
+	// List of child commands which are expensive to construct, see
+	// App.LazyCommands
+	LazyCommands []*LazyCommand
+	// What to do when the arguments do not name any of the child commands
+	NoMatchBehavior NoMatchBehavior
 	// List of flags to parse
 	Flags []Flag
 	// Treat all flags as normal arguments if true
@@ -46,17 +69,39 @@ type Command struct {
 	// Boolean to hide built-in help command but keep help flag
 	// Ignored if HideHelp is true.
 	HideHelpCommand bool
+	// Boolean to add the --print-command flag to this command, like
+	// App.EnablePrintCommand does for the app
+	EnablePrintCommand bool
+	// Boolean to show help and exit with a non-zero code instead of running
+	// the Action when neither flags nor arguments are given
+	ShowHelpWhenEmpty bool
 	// Boolean to hide this command from help or completion
 	Hidden bool
+	// Deprecation message, if set a warning is written when the command is
+	// run and the command is marked as deprecated in help
+	Deprecated string
 	// Boolean to enable short-option handling so user can combine several
 	// single-character bool arguments into one
 	// i.e. foobar -o -v -> foobar -ov
 	UseShortOptionHandling bool
+	// Custom function to split combined short options when
+	// UseShortOptionHandling is enabled, i.e. to decide whether
+	// -abc means -ab -c or -a -b -c
+	ShortFlagResolver ShortFlagResolverFunc
+	// Boolean to make giving a flag which takes a single value more than
+	// once a usage error, rather than keeping the last value
+	ErrorOnDuplicateScalarFlag bool
+	// Time zone in which timestamp flags without their own Timezone parse
+	// values that do not specify one. Defaults to the DefaultTimezone of the
+	// parent command.
+	DefaultTimezone *time.Location
 
 	// Full name of command for help, defaults to full command name, including parent commands.
 	HelpName        string
 	commandNamePath []string
 
+	// Additional sections of the default help, e.g. ENVIRONMENT
+	HelpSections []HelpSection
 	// CustomHelpTemplate the text template for the command help topic.
 	// cli.go uses text/template to render templates. You can
 	// render custom help text by setting this variable.
@@ -90,7 +135,7 @@ func (c *Command) FullName() string {
 
 // Run invokes the command given the context, parses ctx.Args() to generate command-specific flags
 func (c *Command) Run(ctx *Context) (err error) {
-	if len(c.Subcommands) > 0 {
+	if len(c.Subcommands) > 0 || c.DynamicCommands != nil || len(c.LazyCommands) > 0 {
 		return c.startApp(ctx)
 	}
 
@@ -99,14 +144,23 @@ func (c *Command) Run(ctx *Context) (err error) {
 		c.appendFlag(HelpFlag)
 	}
 
+	if c.EnablePrintCommand && PrintCommandFlag != nil {
+		c.appendFlag(PrintCommandFlag)
+	}
+
 	if ctx.App.UseShortOptionHandling {
 		c.UseShortOptionHandling = true
 	}
 
-	set, err := c.parseFlags(ctx.Args(), ctx.shellComplete)
+	set, occurrences, err := c.parseFlags(ctx)
 
 	context := NewContext(ctx.App, set, ctx)
 	context.Command = c
+	context.occurrences = occurrences
+	context.fromEnv = flagsFromEnv(c.Flags)
+	if set != nil && !c.SkipFlagParsing {
+		context.argsTerminated = argsTerminated(set, ctx.Args().Tail())
+	}
 	if checkCommandCompletions(context, c.Name) {
 		return nil
 	}
@@ -117,6 +171,10 @@ func (c *Command) Run(ctx *Context) (err error) {
 			context.App.handleExitCoder(context, err)
 			return err
 		}
+		if c.CompactUsageError {
+			printCompactUsageError(context.App.Writer, err, c.UsageLine())
+			return err
+		}
 		_, _ = fmt.Fprintln(context.App.Writer, "Incorrect Usage:", err.Error())
 		_, _ = fmt.Fprintln(context.App.Writer)
 		_ = ShowCommandHelp(context, c.Name)
@@ -127,12 +185,46 @@ func (c *Command) Run(ctx *Context) (err error) {
 		return nil
 	}
 
+	context.warnDeprecatedCommand(c)
+
+	if c.ShowHelpWhenEmpty && context.NArg() == 0 && context.NumFlags() == 0 {
+		_ = ShowCommandHelp(context, c.Name)
+		err := Exit("", 1)
+		context.App.handleExitCoder(context, err)
+		return err
+	}
+
+	if c.NormalizeFlags != nil {
+		if err := c.NormalizeFlags(context); err != nil {
+			context.App.handleExitCoder(context, err)
+			return err
+		}
+	}
+
+	context.warnDeprecatedEnvVars(c.Flags)
+
+	if err := context.checkTTYFlags(c.Flags); err != nil {
+		return err
+	}
+
 	cerr := context.checkRequiredFlags(c.Flags)
 	if cerr != nil {
 		_ = ShowCommandHelp(context, c.Name)
 		return cerr
 	}
 
+	if c.FileArgs != nil {
+		if err := c.FileArgs.validate(context.Args()); err != nil {
+			return err
+		}
+	}
+
+	if c.ChoiceArgs != nil {
+		if err := c.ChoiceArgs.validate(context.Args()); err != nil {
+			return err
+		}
+	}
+
 	if c.After != nil {
 		defer func() {
 			afterErr := c.After(context)
@@ -155,12 +247,25 @@ func (c *Command) Run(ctx *Context) (err error) {
 		}
 	}
 
+	if c.OnResolved != nil {
+		if err := c.OnResolved(context); err != nil {
+			context.App.handleExitCoder(context, err)
+			return err
+		}
+	}
+
+	if context.validateOnly {
+		return nil
+	}
+
 	if c.Action == nil {
 		c.Action = helpSubcommand.Action
 	}
 
 	context.Command = c
+	context.printCommand()
 	err = c.Action(context)
+	context.App.audit(context, fmt.Sprintf("%s %s", context.App.Name, c.Name))
 
 	if err != nil {
 		context.App.handleExitCoder(context, err)
@@ -168,35 +273,65 @@ func (c *Command) Run(ctx *Context) (err error) {
 	return err
 }
 
-func (c *Command) newFlagSet() (*flag.FlagSet, error) {
-	return flagSet(c.Name, c.Flags)
+// commandParser parses the flags of a command for one run. The settings the
+// command does not set itself are taken from the context it is run in, rather
+// than stored on the command.
+type commandParser struct {
+	*Command
+	parent *Context
+}
+
+func (p *commandParser) newFlagSet() (*flag.FlagSet, error) {
+	loc := p.DefaultTimezone
+	if loc == nil {
+		loc = p.parent.defaultTimezone()
+	}
+	return flagSet(p.Name, p.Flags, loc)
+}
+
+func (p *commandParser) useShortOptionHandling() bool {
+	return p.UseShortOptionHandling
+}
+
+func (p *commandParser) shortFlagResolver() ShortFlagResolverFunc {
+	if p.ShortFlagResolver != nil {
+		return p.ShortFlagResolver
+	}
+	return p.parent.shortFlagResolver()
+}
+
+func (p *commandParser) errorOnDuplicateScalarFlag() bool {
+	return p.ErrorOnDuplicateScalarFlag || p.parent.errorOnDuplicateScalarFlag()
 }
 
-func (c *Command) useShortOptionHandling() bool {
-	return c.UseShortOptionHandling
+func (p *commandParser) flagsToParse() []Flag {
+	return p.Flags
 }
 
-func (c *Command) parseFlags(args Args, shellComplete bool) (*flag.FlagSet, error) {
-	set, err := c.newFlagSet()
+func (c *Command) parseFlags(ctx *Context) (*flag.FlagSet, map[string]int, error) {
+	p := &commandParser{Command: c, parent: ctx}
+	args := ctx.Args()
+
+	set, err := p.newFlagSet()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if c.SkipFlagParsing {
-		return set, set.Parse(append([]string{"--"}, args.Tail()...))
+		return set, nil, set.Parse(append([]string{"--"}, args.Tail()...))
 	}
 
-	err = parseIter(set, c, args.Tail(), shellComplete)
+	occurrences, err := parseIter(set, p, args.Tail(), ctx.shellComplete)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	err = normalizeFlags(c.Flags, set)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return set, nil
+	return set, occurrences, nil
 }
 
 // Names returns the names including short names and aliases.
@@ -214,6 +349,30 @@ func (c *Command) HasName(name string) bool {
 	return false
 }
 
+// Clone returns a deep copy of the command and its subcommands, which can be
+// run independently. Flags are copied without the state of earlier runs, with
+// the exceptions documented on App.Clone.
+func (c *Command) Clone() *Command {
+	n := *c
+	n.Flags = cloneFlags(c.Flags)
+	n.Subcommands = cloneCommands(c.Subcommands)
+	n.LazyCommands = cloneLazyCommands(c.LazyCommands)
+	n.commandNamePath = append([]string(nil), c.commandNamePath...)
+	return &n
+}
+
+func cloneCommands(commands []*Command) []*Command {
+	if commands == nil {
+		return nil
+	}
+
+	cloned := make([]*Command, len(commands))
+	for i, c := range commands {
+		cloned[i] = c.Clone()
+	}
+	return cloned
+}
+
 func (c *Command) startApp(ctx *Context) error {
 	app := &App{
 		Metadata: ctx.App.Metadata,
@@ -234,12 +393,18 @@ func (c *Command) startApp(ctx *Context) error {
 	// set CommandNotFound
 	app.CommandNotFound = ctx.App.CommandNotFound
 	app.CustomAppHelpTemplate = c.CustomHelpTemplate
+	app.HelpSections = c.HelpSections
 
 	// set the flags and commands
 	app.Commands = c.Subcommands
+	app.DynamicCommands = c.DynamicCommands
+	app.LazyCommands = c.LazyCommands
+	app.NoMatchBehavior = c.NoMatchBehavior
 	app.Flags = c.Flags
 	app.HideHelp = c.HideHelp
 	app.HideHelpCommand = c.HideHelpCommand
+	app.EnablePrintCommand = c.EnablePrintCommand
+	app.ShowHelpWhenEmpty = c.ShowHelpWhenEmpty
 
 	app.Version = ctx.App.Version
 	app.HideVersion = true
@@ -249,6 +414,17 @@ func (c *Command) startApp(ctx *Context) error {
 	app.ErrWriter = ctx.App.ErrWriter
 	app.ExitErrHandler = ctx.App.ExitErrHandler
 	app.UseShortOptionHandling = ctx.App.UseShortOptionHandling
+	app.ErrorOnDuplicateScalarFlag = c.ErrorOnDuplicateScalarFlag || ctx.errorOnDuplicateScalarFlag()
+	app.AuditFunc = ctx.App.AuditFunc
+	app.RedactPattern = ctx.App.RedactPattern
+	app.ShortFlagResolver = c.ShortFlagResolver
+	if app.ShortFlagResolver == nil {
+		app.ShortFlagResolver = ctx.shortFlagResolver()
+	}
+	app.DefaultTimezone = c.DefaultTimezone
+	if app.DefaultTimezone == nil {
+		app.DefaultTimezone = ctx.defaultTimezone()
+	}
 
 	app.categories = newCommandCategories()
 	for _, command := range c.Subcommands {
@@ -266,6 +442,12 @@ func (c *Command) startApp(ctx *Context) error {
 	// set the actions
 	app.Before = c.Before
 	app.After = c.After
+	app.NormalizeFlags = c.NormalizeFlags
+	app.OnResolved = c.OnResolved
+	app.group = c
+	app.CompactUsageError = c.CompactUsageError
+	app.FileArgs = c.FileArgs
+	app.ChoiceArgs = c.ChoiceArgs
 	if c.Action != nil {
 		app.Action = c.Action
 	} else {
@@ -280,6 +462,23 @@ func (c *Command) startApp(ctx *Context) error {
 	return app.RunAsSubcommand(ctx)
 }
 
+// UsageLine returns a one-line synopsis of how to invoke the command, e.g.
+// "mytool deploy [flags] <command>"
+func (c *Command) UsageLine() string {
+	name := c.HelpName
+	if name == "" {
+		name = c.Name
+	}
+
+	hasCommands := c.DynamicCommands != nil || len(c.LazyCommands) > 0
+	for _, sub := range c.Subcommands {
+		if sub != helpCommand && sub != helpSubcommand && !sub.Hidden {
+			hasCommands = true
+		}
+	}
+	return usageLine(name, len(c.VisibleFlags()) > 0, hasCommands, c.ArgsUsage, c.ChoiceArgs)
+}
+
 // VisibleFlags returns a slice of the Flags with Hidden=false
 func (c *Command) VisibleFlags() []Flag {
 	return visibleFlags(c.Flags)
@@ -300,3 +499,73 @@ func hasCommand(commands []*Command, command *Command) bool {
 
 	return false
 }
+
+// LazyCommand describes a command which is expensive to construct. It is
+// listed in help and completion by its description, and the Factory is only
+// called once the command is needed, e.g. to run it.
+type LazyCommand struct {
+	// The name of the command
+	Name string
+	// A list of aliases for the command
+	Aliases []string
+	// A short description of the usage of this command
+	Usage string
+	// The category the command is part of
+	Category string
+	// Boolean to hide this command from help or completion
+	Hidden bool
+	// Function constructing the command, it is called at most once
+	Factory func() *Command
+
+	built *Command
+}
+
+// Names returns the names including short names and aliases.
+func (l *LazyCommand) Names() []string {
+	return append([]string{l.Name}, l.Aliases...)
+}
+
+// HasName returns true if LazyCommand.Name or one of its aliases matches
+// given name
+func (l *LazyCommand) HasName(name string) bool {
+	for _, n := range l.Names() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// command returns the command, calling the Factory the first time
+func (l *LazyCommand) command() *Command {
+	if l.built == nil && l.Factory != nil {
+		l.built = l.Factory()
+	}
+	return l.built
+}
+
+// listing returns a command with the description of the lazy command, to
+// list it without constructing it
+func (l *LazyCommand) listing() *Command {
+	return &Command{
+		Name:     l.Name,
+		Aliases:  l.Aliases,
+		Usage:    l.Usage,
+		Category: l.Category,
+		Hidden:   l.Hidden,
+	}
+}
+
+func cloneLazyCommands(commands []*LazyCommand) []*LazyCommand {
+	if commands == nil {
+		return nil
+	}
+
+	cloned := make([]*LazyCommand, len(commands))
+	for i, l := range commands {
+		n := *l
+		n.built = nil
+		cloned[i] = &n
+	}
+	return cloned
+}
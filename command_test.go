@@ -6,8 +6,11 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestCommandFlagParsing(t *testing.T) {
@@ -103,6 +106,120 @@ func TestParseAndRunShortOpts(t *testing.T) {
 	}
 }
 
+func TestParseShortOptsExactMatchAndSplit(t *testing.T) {
+	cases := []struct {
+		testArgs []string
+		expected map[string]bool
+	}{
+		{testArgs: []string{"foo", "test", "-ab"}, expected: map[string]bool{"a": false, "b": false, "ab": true}},
+		{testArgs: []string{"foo", "test", "-ba"}, expected: map[string]bool{"a": true, "b": true, "ab": false}},
+	}
+
+	for _, c := range cases {
+		var ctx *Context
+		cmd := &Command{
+			Name:                   "test",
+			UseShortOptionHandling: true,
+			Flags: []Flag{
+				&BoolFlag{Name: "a"},
+				&BoolFlag{Name: "b"},
+				&BoolFlag{Name: "ab"},
+			},
+			Action: func(c *Context) error {
+				ctx = c
+				return nil
+			},
+		}
+
+		app := newTestApp()
+		app.Commands = []*Command{cmd}
+
+		err := app.Run(c.testArgs)
+
+		expect(t, err, nil)
+		for name, value := range c.expected {
+			expect(t, ctx.Bool(name), value)
+		}
+	}
+}
+
+func TestParseShortOptsLongestMatch(t *testing.T) {
+	cases := []struct {
+		testArgs []string
+		expected map[string]bool
+	}{
+		{testArgs: []string{"foo", "test", "-abc"}, expected: map[string]bool{"a": false, "b": false, "ab": true, "c": true}},
+		{testArgs: []string{"foo", "test", "-cab"}, expected: map[string]bool{"a": false, "b": false, "ab": true, "c": true}},
+		{testArgs: []string{"foo", "test", "-bac"}, expected: map[string]bool{"a": true, "b": true, "ab": false, "c": true}},
+	}
+
+	for _, c := range cases {
+		var ctx *Context
+		cmd := &Command{
+			Name:                   "test",
+			UseShortOptionHandling: true,
+			Flags: []Flag{
+				&BoolFlag{Name: "a"},
+				&BoolFlag{Name: "b"},
+				&BoolFlag{Name: "ab"},
+				&BoolFlag{Name: "c"},
+			},
+			Action: func(c *Context) error {
+				ctx = c
+				return nil
+			},
+		}
+
+		app := newTestApp()
+		app.Commands = []*Command{cmd}
+
+		err := app.Run(c.testArgs)
+
+		expect(t, err, nil)
+		for name, value := range c.expected {
+			expect(t, ctx.Bool(name), value)
+		}
+	}
+}
+
+func TestParseShortOptsWithResolver(t *testing.T) {
+	var resolved []string
+	var ctx *Context
+	cmd := &Command{
+		Name:                   "test",
+		UseShortOptionHandling: true,
+		ShortFlagResolver: func(remaining string) ([]string, bool) {
+			resolved = append(resolved, remaining)
+			if strings.HasPrefix(remaining, "ab") {
+				return []string{"ab", remaining[2:]}, true
+			}
+			return nil, false
+		},
+		Flags: []Flag{
+			&BoolFlag{Name: "ab"},
+			&BoolFlag{Name: "c"},
+		},
+		Action: func(c *Context) error {
+			ctx = c
+			return nil
+		},
+	}
+
+	app := newTestApp()
+	app.Commands = []*Command{cmd}
+
+	err := app.Run([]string{"foo", "test", "-abc"})
+
+	expect(t, err, nil)
+	expect(t, resolved, []string{"abc"})
+	expect(t, ctx.Bool("ab"), true)
+	expect(t, ctx.Bool("c"), true)
+
+	err = app.Run([]string{"foo", "test", "-abx"})
+
+	expect(t, err, errors.New("flag provided but not defined: -abx"))
+}
+
 func TestCommand_Run_DoesNotOverwriteErrorFromBefore(t *testing.T) {
 	app := &App{
 		Commands: []*Command{
@@ -422,3 +539,455 @@ func TestCommand_CanAddVFlagOnCommands(t *testing.T) {
 	err := app.Run([]string{"foo", "bar"})
 	expect(t, err, nil)
 }
+
+func TestCommand_NormalizeFlags(t *testing.T) {
+	var dir string
+	app := &App{
+		Writer: ioutil.Discard,
+		Commands: []*Command{
+			{
+				Name:  "build",
+				Flags: []Flag{&PathFlag{Name: "dir", Required: true}},
+				NormalizeFlags: func(c *Context) error {
+					abs, err := filepath.Abs(c.Path("dir"))
+					if err != nil {
+						return err
+					}
+					return c.Set("dir", abs)
+				},
+				Action: func(c *Context) error {
+					dir = c.Path("dir")
+					return nil
+				},
+			},
+		},
+	}
+
+	err := app.Run([]string{"foo", "build", "--dir", "out"})
+	expect(t, err, nil)
+
+	expected, _ := filepath.Abs("out")
+	expect(t, dir, expected)
+
+	app.Commands[0].NormalizeFlags = func(c *Context) error {
+		return errors.New("cannot normalize")
+	}
+	dir = ""
+	err = app.Run([]string{"foo", "build", "--dir", "out"})
+	if err == nil || err.Error() != "cannot normalize" {
+		t.Errorf("expected normalize error, got %v", err)
+	}
+	expect(t, dir, "")
+}
+
+func TestCommand_Deprecated(t *testing.T) {
+	output := &bytes.Buffer{}
+	errOutput := &bytes.Buffer{}
+	ran := false
+	app := &App{
+		Name:      "foo",
+		Writer:    output,
+		ErrWriter: errOutput,
+		Commands: []*Command{
+			{
+				Name:       "old",
+				Usage:      "does the old thing",
+				Deprecated: "use new instead",
+				Action: func(c *Context) error {
+					ran = true
+					return nil
+				},
+			},
+			{
+				Name:        "legacy",
+				Deprecated:  "use new instead",
+				Subcommands: []*Command{{Name: "sub", Action: func(c *Context) error { return nil }}},
+			},
+		},
+	}
+
+	err := app.Run([]string{"foo", "old"})
+	expect(t, err, nil)
+	expect(t, ran, true)
+	expect(t, errOutput.String(), "warning: command \"old\" is deprecated: use new instead\n")
+
+	errOutput.Reset()
+	err = app.Run([]string{"foo", "old", "--help"})
+	expect(t, err, nil)
+	expect(t, errOutput.String(), "")
+
+	err = app.Run([]string{"foo", "legacy", "--help"})
+	expect(t, err, nil)
+	expect(t, errOutput.String(), "")
+
+	err = app.Run([]string{"foo", "legacy", "sub"})
+	expect(t, err, nil)
+	expect(t, errOutput.String(), "warning: command \"legacy\" is deprecated: use new instead\n")
+
+	err = app.Run([]string{"foo", "help"})
+	expect(t, err, nil)
+	if !strings.Contains(output.String(), "does the old thing (deprecated)") {
+		t.Errorf("expected the command to be marked deprecated in help, got: %q", output.String())
+	}
+}
+
+func TestCommand_UsageLine(t *testing.T) {
+	cmd := &Command{
+		Name:        "deploy",
+		HelpName:    "mytool deploy",
+		Flags:       []Flag{&BoolFlag{Name: "force"}},
+		Subcommands: []*Command{{Name: "app"}, {Name: "secret", Hidden: true}},
+	}
+	expect(t, cmd.UsageLine(), "mytool deploy [flags] <command>")
+
+	cmd = &Command{Name: "copy", ArgsUsage: "SRC... DST"}
+	expect(t, cmd.UsageLine(), "copy SRC... DST")
+
+	app := &App{Name: "mytool", Flags: []Flag{&BoolFlag{Name: "verbose"}}, Commands: []*Command{cmd}}
+	app.Setup()
+	expect(t, app.UsageLine(), "mytool [flags] <command>")
+}
+
+func TestCommand_CompactUsageError(t *testing.T) {
+	output := &bytes.Buffer{}
+	app := &App{
+		Name:   "mytool",
+		Writer: output,
+		Commands: []*Command{
+			{
+				Name:              "deploy",
+				Flags:             []Flag{&IntFlag{Name: "replicas"}},
+				CompactUsageError: true,
+				Action:            func(c *Context) error { return nil },
+			},
+		},
+	}
+
+	err := app.Run([]string{"mytool", "deploy", "--replicas", "many"})
+	if err == nil {
+		t.Fatal("expected a usage error")
+	}
+	expect(t, output.String(), "Incorrect Usage: "+err.Error()+"\nUsage: mytool deploy [flags] [arguments...]\n")
+}
+
+func TestCommand_FileArgs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "urfave_cli_file_args")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "a.txt")
+	_ = ioutil.WriteFile(file, []byte("a"), 0644)
+	missing := filepath.Join(dir, "missing.txt")
+
+	cases := []struct {
+		fileArgs FileArgs
+		args     []string
+		err      string
+	}{
+		{FileArgs{Min: 1}, []string{file, dir}, ""},
+		{FileArgs{Min: 1}, []string{file, missing}, "argument 2: file not found: " + missing},
+		{FileArgs{Min: 1}, []string{}, "expected at least 1 arguments, got 0"},
+		{FileArgs{Max: 1}, []string{file, file}, "expected at most 1 arguments, got 2"},
+		{FileArgs{Mode: FileArgsFile, Readable: true}, []string{file}, ""},
+		{FileArgs{Mode: FileArgsFile}, []string{dir}, "argument 1: is a directory: " + dir},
+		{FileArgs{Mode: FileArgsDir}, []string{dir, file}, "argument 2: not a directory: " + file},
+	}
+
+	for _, c := range cases {
+		fileArgs := c.fileArgs
+		ran := false
+		app := &App{
+			Writer: ioutil.Discard,
+			Commands: []*Command{
+				{
+					Name:     "cat",
+					FileArgs: &fileArgs,
+					Action: func(*Context) error {
+						ran = true
+						return nil
+					},
+				},
+			},
+		}
+
+		err := app.Run(append([]string{"foo", "cat"}, c.args...))
+		if c.err == "" {
+			expect(t, err, nil)
+			expect(t, ran, true)
+		} else {
+			expect(t, err, errors.New(c.err))
+			expect(t, ran, false)
+		}
+	}
+}
+
+func TestCommand_OnResolved(t *testing.T) {
+	var calls []string
+	var resolved map[string]string
+	app := &App{
+		Writer: ioutil.Discard,
+		Flags:  []Flag{&StringFlag{Name: "region", Value: "eu"}},
+		OnResolved: func(c *Context) error {
+			calls = append(calls, "root")
+			return nil
+		},
+		Commands: []*Command{
+			{
+				Name: "db",
+				OnResolved: func(c *Context) error {
+					calls = append(calls, "db")
+					return nil
+				},
+				Subcommands: []*Command{
+					{
+						Name:  "migrate",
+						Flags: []Flag{&IntFlag{Name: "steps", Required: true}},
+						Before: func(c *Context) error {
+							calls = append(calls, "before")
+							return nil
+						},
+						OnResolved: func(c *Context) error {
+							calls = append(calls, "migrate")
+							resolved = map[string]string{
+								"region": c.String("region"),
+								"steps":  fmt.Sprint(c.Int("steps")),
+							}
+							return nil
+						},
+						Action: func(c *Context) error {
+							calls = append(calls, "action")
+							return nil
+						},
+					},
+				},
+			},
+		},
+	}
+
+	err := app.Run([]string{"foo", "--region", "us", "db", "migrate", "--steps", "3"})
+	expect(t, err, nil)
+	expect(t, calls, []string{"before", "migrate", "action"})
+	expect(t, resolved, map[string]string{"region": "us", "steps": "3"})
+
+	calls = nil
+	err = app.Run([]string{"foo", "db", "migrate"})
+	if err == nil {
+		t.Error("expected the missing required flag to be reported")
+	}
+	expect(t, len(calls), 0)
+
+	calls = nil
+	app.Commands[0].Subcommands[0].OnResolved = func(c *Context) error {
+		return errors.New("cannot store config")
+	}
+	err = app.Run([]string{"foo", "db", "migrate", "--steps", "3"})
+	expect(t, err, errors.New("cannot store config"))
+	expect(t, calls, []string{"before"})
+}
+
+func TestCommand_ShowHelpWhenEmpty(t *testing.T) {
+	output := &bytes.Buffer{}
+	ran := false
+	app := &App{
+		Name:   "app",
+		Writer: output,
+		Commands: []*Command{
+			{
+				Name:              "build",
+				Usage:             "build the project",
+				ShowHelpWhenEmpty: true,
+				Flags:             []Flag{&BoolFlag{Name: "release"}},
+				Action: func(*Context) error {
+					ran = true
+					return nil
+				},
+			},
+		},
+	}
+
+	lastExitCode = 0
+	err := app.Run([]string{"app", "build"})
+	if ec, ok := err.(ExitCoder); !ok || ec.ExitCode() != 1 {
+		t.Errorf("expected an exit code of 1, got %v", err)
+	}
+	expect(t, lastExitCode, 1)
+	expect(t, ran, false)
+	if !strings.Contains(output.String(), "app build - build the project") {
+		t.Errorf("expected help for the command, got: %q", output.String())
+	}
+
+	err = app.Run([]string{"app", "build", "--release"})
+	expect(t, err, nil)
+	expect(t, ran, true)
+
+	ran = false
+	err = app.Run([]string{"app", "build", "target"})
+	expect(t, err, nil)
+	expect(t, ran, true)
+}
+
+func TestCommand_ChoiceArgs(t *testing.T) {
+	output := &bytes.Buffer{}
+	var direction string
+	app := &App{
+		Name:   "app",
+		Writer: output,
+		Commands: []*Command{
+			{
+				Name:       "scale",
+				Usage:      "scale the cluster",
+				ChoiceArgs: &ChoiceArgs{AllowedValues: []string{"up", "down"}, Min: 1, Max: 1},
+				Action: func(ctx *Context) error {
+					direction = ctx.Args().First()
+					return nil
+				},
+			},
+		},
+	}
+
+	err := app.Run([]string{"app", "scale", "up"})
+	expect(t, err, nil)
+	expect(t, direction, "up")
+
+	err = app.Run([]string{"app", "scale", "sideways"})
+	if err == nil || err.Error() != `argument 1: invalid value "sideways", must be one of: up, down` {
+		t.Errorf("expected an error listing the choices, got %v", err)
+	}
+
+	err = app.Run([]string{"app", "scale"})
+	if err == nil || err.Error() != "expected at least 1 arguments, got 0" {
+		t.Errorf("expected an error for the missing argument, got %v", err)
+	}
+
+	expect(t, app.Commands[0].UsageLine(), "app scale [flags] <up|down>")
+
+	output.Reset()
+	_ = app.Run([]string{"app", "scale", "--help"})
+	if !strings.Contains(output.String(), "app scale [command options] <up|down>") {
+		t.Errorf("expected the choices in the usage, got: %q", output.String())
+	}
+
+	candidates, err := app.CompletionJSON([]string{"app", "scale", "d"})
+	expect(t, err, nil)
+	expect(t, candidates, []Candidate{{Value: "down", Type: CandidateArgument}})
+}
+
+func TestCommand_ErrorOnDuplicateScalarFlag(t *testing.T) {
+	var output string
+	var tags []string
+	cmd := &Command{
+		Name: "build",
+		Flags: []Flag{
+			&StringFlag{Name: "output", Aliases: []string{"o"}},
+			&StringSliceFlag{Name: "tag"},
+		},
+		Action: func(ctx *Context) error {
+			output = ctx.String("output")
+			tags = ctx.StringSlice("tag")
+			return nil
+		},
+	}
+	app := &App{Name: "app", Writer: ioutil.Discard, Commands: []*Command{cmd}}
+
+	err := app.Run([]string{"app", "build", "--output", "a", "--output", "b"})
+	expect(t, err, nil)
+	expect(t, output, "b")
+
+	cmd.ErrorOnDuplicateScalarFlag = true
+	err = app.Run([]string{"app", "build", "--output", "a", "--output", "b"})
+	if err == nil || err.Error() != "flag --output specified multiple times" {
+		t.Errorf("expected an error for the duplicated flag, got %v", err)
+	}
+
+	err = app.Run([]string{"app", "build", "-o", "a", "--tag", "x", "--tag", "y"})
+	expect(t, err, nil)
+	expect(t, output, "a")
+	expect(t, tags, []string{"x", "y"})
+
+	err = app.Run([]string{"app", "build", "-o", "a", "-o", "b"})
+	if err == nil || err.Error() != "flag -o specified multiple times" {
+		t.Errorf("expected an error naming the repeated alias, got %v", err)
+	}
+}
+
+func TestCommand_Run_InheritsSettingsPerRun(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	var start *time.Time
+	cmd := &Command{
+		Name: "schedule",
+		Flags: []Flag{
+			&StringFlag{Name: "output"},
+			&TimestampFlag{Name: "start", Layout: "2006-01-02 15:04"},
+		},
+		Action: func(ctx *Context) error {
+			start = ctx.Timestamp("start")
+			return nil
+		},
+	}
+	app := &App{
+		Name:                       "app",
+		Writer:                     ioutil.Discard,
+		ErrorOnDuplicateScalarFlag: true,
+		DefaultTimezone:            loc,
+		Commands:                   []*Command{cmd},
+	}
+
+	err := app.Run([]string{"app", "schedule", "--start", "2021-03-01 09:00"})
+	expect(t, err, nil)
+	expect(t, start.Location(), loc)
+
+	err = app.Run([]string{"app", "schedule", "--output", "a", "--output", "b"})
+	if err == nil {
+		t.Errorf("expected an error for the duplicated flag")
+	}
+
+	// the settings of the app are not copied onto the command
+	expect(t, cmd.ErrorOnDuplicateScalarFlag, false)
+	expect(t, cmd.DefaultTimezone == nil, true)
+
+	other := &App{Name: "other", Writer: ioutil.Discard, Commands: []*Command{cmd}}
+	err = other.Run([]string{"other", "schedule", "--output", "a", "--output", "b", "--start", "2021-03-01 09:00"})
+	expect(t, err, nil)
+	expect(t, start.Location(), time.UTC)
+}
+
+type repeatableList []string
+
+func (l *repeatableList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func (l *repeatableList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *repeatableList) IsRepeatable() bool {
+	return true
+}
+
+func TestCommand_ErrorOnDuplicateScalarFlag_RepeatableFlags(t *testing.T) {
+	var verbosity int
+	hosts := &repeatableList{}
+	app := &App{
+		Name:                       "app",
+		Writer:                     ioutil.Discard,
+		ErrorOnDuplicateScalarFlag: true,
+		UseShortOptionHandling:     true,
+		Flags: []Flag{
+			&BoolFlag{Name: "verbose", Aliases: []string{"v"}},
+			&GenericFlag{Name: "host", Value: hosts},
+		},
+		Action: func(ctx *Context) error {
+			verbosity = ctx.Count("v")
+			return nil
+		},
+	}
+
+	err := app.Run([]string{"app", "-v", "-v", "-vv", "--host", "a", "--host", "b"})
+	expect(t, err, nil)
+	expect(t, verbosity, 4)
+	expect(t, []string(*hosts), []string{"a", "b"})
+}
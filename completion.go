@@ -0,0 +1,245 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// CandidateType is the kind of a completion Candidate
+type CandidateType string
+
+const (
+	// CandidateCommand is the name of a command
+	CandidateCommand CandidateType = "command"
+	// CandidateFlag is the name of a flag, including its dashes
+	CandidateFlag CandidateType = "flag"
+	// CandidateValue is a value of a flag
+	CandidateValue CandidateType = "value"
+	// CandidateArgument is an allowed value of an argument
+	CandidateArgument CandidateType = "argument"
+)
+
+// Candidate is a completion candidate, e.g. for an editor integration
+type Candidate struct {
+	Value       string        `json:"value"`
+	Description string        `json:"description,omitempty"`
+	Type        CandidateType `json:"type"`
+}
+
+// completionLevel holds what may be completed at a command level
+type completionLevel struct {
+	flags    []Flag
+	commands []*Command
+	choices  *ChoiceArgs
+}
+
+// CompletionJSON returns the completion candidates for the last of the given
+// arguments, which include the program name like os.Args. Unlike shell
+// completion, candidates are returned in a structured form suitable for JSON
+// encoding rather than written to the Writer. The command to complete for is
+// resolved by running the app in completion mode, like shell completion does.
+func (a *App) CompletionJSON(arguments []string) ([]Candidate, error) {
+	if len(arguments) == 0 {
+		return nil, errors.New("no arguments to complete")
+	}
+
+	words := arguments[1:]
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur := words[len(words)-1]
+	prev := ""
+	if len(words) > 1 {
+		prev = words[len(words)-2]
+	}
+
+	candidates := []Candidate{}
+	completeFunc := func(ctx *Context, cmd *Command) {
+		candidates = completionCandidates(ctx, cmd, prev, cur)
+	}
+
+	args := append([]string{arguments[0]}, words[:len(words)-1]...)
+	if err := a.run(&Context{Context: context.Background(), completeFunc: completeFunc}, args); err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// completionCandidates returns the candidates for cur, the argument after
+// prev, at the level completion was resolved to: the command cmd or, if it is
+// nil, the app of ctx. After a "--" only arguments are completed.
+func completionCandidates(ctx *Context, cmd *Command, prev, cur string) []Candidate {
+	level := appCompletionLevel(ctx)
+	if cmd != nil {
+		level = commandCompletionLevel(cmd)
+	}
+
+	if !ctx.ArgsTerminated() {
+		if f := lookupFlagArg(level.flags, prev); f != nil && flagTakesValue(f) && !strings.Contains(prev, "=") {
+			return valueCandidates(ctx, cur, f)
+		}
+		if i := strings.Index(cur, "="); strings.HasPrefix(cur, "-") && i >= 0 {
+			f := lookupFlagArg(level.flags, cur)
+			if f == nil || !flagTakesValue(f) {
+				return []Candidate{}
+			}
+			candidates := valueCandidates(ctx, cur[i+1:], f)
+			for j := range candidates {
+				candidates[j].Value = cur[:i+1] + candidates[j].Value
+			}
+			return candidates
+		}
+		if strings.HasPrefix(cur, "-") {
+			return flagCandidates(cur, level.flags)
+		}
+	}
+	return append(commandCandidates(cur, level.commands), choiceCandidates(cur, level.choices)...)
+}
+
+func appCompletionLevel(ctx *Context) completionLevel {
+	a := ctx.App
+	return completionLevel{
+		flags:    a.VisibleFlags(),
+		commands: append(append(append([]*Command{}, a.Commands...), a.lazyCommandListing()...), a.discoverCommands(ctx)...),
+		choices:  a.ChoiceArgs,
+	}
+}
+
+// commandCompletionLevel is the level of a command without subcommands, those
+// with subcommands are completed by the app they run
+func commandCompletionLevel(c *Command) completionLevel {
+	return completionLevel{
+		flags:    c.VisibleFlags(),
+		commands: []*Command{},
+		choices:  c.ChoiceArgs,
+	}
+}
+
+func commandCandidates(cur string, commands []*Command) []Candidate {
+	candidates := []Candidate{}
+	for _, c := range commands {
+		if c.Hidden {
+			continue
+		}
+		for _, name := range c.Names() {
+			if strings.HasPrefix(name, cur) {
+				candidates = append(candidates, Candidate{Value: name, Description: c.Usage, Type: CandidateCommand})
+			}
+		}
+	}
+	return candidates
+}
+
+func choiceCandidates(cur string, choices *ChoiceArgs) []Candidate {
+	if choices == nil {
+		return nil
+	}
+
+	var candidates []Candidate
+	for _, value := range choices.AllowedValues {
+		if strings.HasPrefix(value, cur) {
+			candidates = append(candidates, Candidate{Value: value, Type: CandidateArgument})
+		}
+	}
+	return candidates
+}
+
+func flagCandidates(cur string, flags []Flag) []Candidate {
+	candidates := []Candidate{}
+	for _, f := range flags {
+		if vf, ok := f.(VisibleFlag); ok && !vf.IsVisible() || flagOptions(f).EnvOnly {
+			continue
+		}
+
+		usage := ""
+		if df, ok := f.(DocGenerationFlag); ok {
+			_, usage = unquoteUsage(df.GetUsage())
+		}
+
+		for _, name := range f.Names() {
+			name = prefixFor(name) + strings.TrimSpace(name)
+			if strings.HasPrefix(name, cur) {
+				candidates = append(candidates, Candidate{Value: name, Description: usage, Type: CandidateFlag})
+			}
+		}
+	}
+	return candidates
+}
+
+func valueCandidates(ctx *Context, cur string, f Flag) []Candidate {
+	candidates := []Candidate{}
+	completeValues(ctx, f, func(value string) bool {
+		if strings.HasPrefix(value, cur) {
+			candidates = append(candidates, Candidate{Value: value, Type: CandidateValue})
+		}
+		return true
+	})
+	return candidates
+}
+
+// completeValues calls fn with each value to offer when completing the value
+// of a flag, as it is produced by the CompleteValues function of the flag or
+// else by flagValueCompletions. It stops and cancels the context passed to
+// CompleteValues once fn returns false.
+func completeValues(ctx *Context, f Flag, fn func(string) bool) {
+	complete := flagCompleteValuesFunc(f)
+	if complete == nil {
+		for _, value := range flagValueCompletions(f) {
+			if !fn(value) {
+				return
+			}
+		}
+		return
+	}
+
+	cancelCtx, cancel := context.WithCancel(ctx.Context)
+	defer cancel()
+
+	valuesCtx := *ctx
+	valuesCtx.Context = cancelCtx
+	for value := range complete(&valuesCtx) {
+		if !fn(value) {
+			return
+		}
+	}
+}
+
+// flagValueCompletions returns the values to offer when completing the value
+// of a flag, which is its default value if it has one
+func flagValueCompletions(f Flag) []string {
+	if !hasDefaultValue(f) || isSensitive(f) {
+		return nil
+	}
+
+	value := flagValue(f).FieldByName("Value").Interface()
+	if fv, ok := value.(flag.Value); ok {
+		return []string{flagValueString(fv)}
+	}
+	return []string{fmt.Sprint(value)}
+}
+
+// lookupFlagArg returns the flag named by a command line argument such as
+// "--name" or "--name=value", or nil if there is none
+func lookupFlagArg(flags []Flag, arg string) Flag {
+	name := strings.TrimLeft(arg, "-")
+	if i := strings.Index(name, "="); i >= 0 {
+		name = name[:i]
+	}
+
+	for _, f := range flags {
+		for _, n := range f.Names() {
+			if n == name {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+func flagTakesValue(f Flag) bool {
+	df, ok := f.(DocGenerationFlag)
+	return ok && df.TakesValue()
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestApp_CompletionJSON(t *testing.T) {
+	app := &App{
+		Name: "greet",
+		Commands: []*Command{
+			{
+				Name:  "hello",
+				Usage: "say hello",
+				Flags: []Flag{
+					&StringFlag{Name: "name", Aliases: []string{"n"}, Usage: "who to greet", Value: "world"},
+					&BoolFlag{Name: "loud", Usage: "shout it"},
+					&BoolFlag{Name: "secret", Hidden: true},
+				},
+			},
+			{Name: "hidden", Hidden: true},
+		},
+	}
+
+	candidates, err := app.CompletionJSON([]string{"greet", "hello", "--lo"})
+	expect(t, err, nil)
+	expect(t, candidates, []Candidate{
+		{Value: "--loud", Description: "shout it", Type: CandidateFlag},
+	})
+
+	candidates, err = app.CompletionJSON([]string{"greet", "hello", "-n", "bob", "--n"})
+	expect(t, err, nil)
+	expect(t, candidates, []Candidate{
+		{Value: "--name", Description: "who to greet", Type: CandidateFlag},
+	})
+
+	candidates, err = app.CompletionJSON([]string{"greet", "hello", "--name", ""})
+	expect(t, err, nil)
+	expect(t, candidates, []Candidate{
+		{Value: "world", Type: CandidateValue},
+	})
+
+	candidates, err = app.CompletionJSON([]string{"greet", "hello", "--name", "x"})
+	expect(t, err, nil)
+	expect(t, candidates, []Candidate{})
+
+	candidates, err = app.CompletionJSON([]string{"greet", "hello", "--name=w"})
+	expect(t, err, nil)
+	expect(t, candidates, []Candidate{
+		{Value: "--name=world", Type: CandidateValue},
+	})
+
+	candidates, err = app.CompletionJSON([]string{"greet", "h"})
+	expect(t, err, nil)
+	expect(t, candidates, []Candidate{
+		{Value: "hello", Description: "say hello", Type: CandidateCommand},
+		{Value: "help", Description: "Shows a list of commands or help for one command", Type: CandidateCommand},
+		{Value: "h", Description: "Shows a list of commands or help for one command", Type: CandidateCommand},
+	})
+
+	out, err := json.Marshal(candidates[:1])
+	expect(t, err, nil)
+	expect(t, string(out), `[{"value":"hello","description":"say hello","type":"command"}]`)
+
+	_, err = app.CompletionJSON(nil)
+	if err == nil {
+		t.Fatal("expected error for empty arguments")
+	}
+}
+
+func TestApp_CompletionJSON_ResolvesLikeShellCompletion(t *testing.T) {
+	var discovered int
+	app := &App{
+		Name: "app",
+		Commands: []*Command{
+			{
+				Name: "plugins",
+				DynamicCommands: func(c *Context) []*Command {
+					discovered++
+					return []*Command{{Name: "lint", Usage: "lint the code"}}
+				},
+			},
+			{
+				Name:       "run",
+				Flags:      []Flag{&StringFlag{Name: "name", Value: "world"}},
+				ChoiceArgs: &ChoiceArgs{AllowedValues: []string{"-x", "fast"}},
+			},
+		},
+	}
+
+	candidates, err := app.CompletionJSON([]string{"app", "plugins", "l"})
+	expect(t, err, nil)
+	expect(t, candidates, []Candidate{
+		{Value: "lint", Description: "lint the code", Type: CandidateCommand},
+	})
+	expect(t, discovered, 1)
+
+	candidates, err = app.CompletionJSON([]string{"app", "run", "--", "-"})
+	expect(t, err, nil)
+	expect(t, candidates, []Candidate{
+		{Value: "-x", Type: CandidateArgument},
+	})
+
+	candidates, err = app.CompletionJSON([]string{"app", "run", "--", "--name", ""})
+	expect(t, err, nil)
+	expect(t, candidates, []Candidate{
+		{Value: "-x", Type: CandidateArgument},
+		{Value: "fast", Type: CandidateArgument},
+	})
+
+	candidates, err = app.CompletionJSON([]string{"app", "run", "-"})
+	expect(t, err, nil)
+	expect(t, candidates, []Candidate{
+		{Value: "--name", Type: CandidateFlag},
+		{Value: "--help", Description: "show help", Type: CandidateFlag},
+		{Value: "-h", Description: "show help", Type: CandidateFlag},
+	})
+}
@@ -3,7 +3,11 @@ package cli
 import (
 	"context"
 	"flag"
+	"fmt"
+	"io"
+	"os"
 	"strings"
+	"time"
 )
 
 // Context is a type that is passed through to
@@ -17,6 +21,16 @@ type Context struct {
 	shellComplete bool
 	flagSet       *flag.FlagSet
 	parentContext *Context
+	occurrences   map[string]int
+	// names of the flags set from their env vars or file in this run
+	fromEnv map[string]bool
+	// whether a "--" ended the flags of this context
+	argsTerminated bool
+	// whether Actions are skipped, see App.RunValidateOnly
+	validateOnly bool
+	// called instead of the BashComplete functions while completing, with the
+	// context and command completion resolved to, see App.CompletionJSON
+	completeFunc func(ctx *Context, cmd *Command)
 }
 
 // NewContext creates a new context. For use in when invoking an App or Command action.
@@ -25,6 +39,8 @@ func NewContext(app *App, set *flag.FlagSet, parentCtx *Context) *Context {
 	if parentCtx != nil {
 		c.Context = parentCtx.Context
 		c.shellComplete = parentCtx.shellComplete
+		c.validateOnly = parentCtx.validateOnly
+		c.completeFunc = parentCtx.completeFunc
 		if parentCtx.flagSet == nil {
 			parentCtx.flagSet = &flag.FlagSet{}
 		}
@@ -73,6 +89,66 @@ func (c *Context) IsSet(name string) bool {
 	return false
 }
 
+// IsExplicitlySet returns true if the user gave the named flag, whatever its
+// value, i.e. if it was given on the command line for this context or any of
+// its ancestors, or set from its environment variables or file. Unlike IsSet,
+// values set with Context.Set do not count, while a flag given for an ancestor
+// counts even if the command defines a flag of the same name.
+func (c *Context) IsExplicitlySet(name string) bool {
+	for _, ctx := range c.Lineage() {
+		if ctx.flagSet == nil || ctx.flagSet.Lookup(name) == nil {
+			continue
+		}
+		if ctx.localOccurrences(name) > 0 || ctx.fromEnv[name] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FlagOccurrences returns the number of times the named flag was given on the
+// command line under any of its names. Each occurrence counts once, so for a
+// slice flag this may differ from the number of values it holds.
+func (c *Context) FlagOccurrences(name string) int {
+	for _, ctx := range c.Lineage() {
+		if ctx.flagSet.Lookup(name) != nil {
+			return ctx.localOccurrences(name)
+		}
+	}
+
+	return 0
+}
+
+// Count returns the number of times the named flag was given on the command
+// line, summed over this context and all of its ancestors which define it.
+// Unlike FlagOccurrences, a flag such as -v defined by both the app and a
+// command counts for "app -v cmd -vv" as 3.
+func (c *Context) Count(name string) int {
+	count := 0
+	for _, ctx := range c.Lineage() {
+		if ctx.flagSet != nil && ctx.flagSet.Lookup(name) != nil {
+			count += ctx.localOccurrences(name)
+		}
+	}
+	return count
+}
+
+// localOccurrences returns the number of times the named flag was given for
+// this context under any of its names
+func (c *Context) localOccurrences(name string) int {
+	names := []string{name}
+	if f := c.lookupFlag(name); f != nil {
+		names = f.Names()
+	}
+
+	count := 0
+	for _, n := range names {
+		count += c.occurrences[n]
+	}
+	return count
+}
+
 // LocalFlagNames returns a slice of flag names used in this context.
 func (c *Context) LocalFlagNames() []string {
 	var names []string
@@ -90,6 +166,28 @@ func (c *Context) FlagNames() []string {
 	return names
 }
 
+// ExportEnv returns the flags set for this context and its ancestors as
+// NAME=value entries, e.g. to pass them to a subprocess. NAME is the first of
+// the flag's EnvVars, or else its name in upper case. Sensitive flags and
+// flags whose value matches the RedactPattern of the app are left out, rather
+// than passing a masked value on.
+func (c *Context) ExportEnv() []string {
+	var env []string
+	for _, f := range c.setFlags() {
+		if c.maskedValue(f) == sensitiveMask {
+			continue
+		}
+
+		name := envVarName(f.name)
+		if envVars := flagStringSliceField(f.flag, "EnvVars"); len(envVars) > 0 {
+			name = strings.TrimSpace(envVars[0])
+		}
+
+		env = append(env, name+"="+f.value)
+	}
+	return env
+}
+
 // Lineage returns *this* context and all of its ancestor contexts in order from
 // child to parent
 func (c *Context) Lineage() []*Context {
@@ -102,6 +200,39 @@ func (c *Context) Lineage() []*Context {
 	return lineage
 }
 
+// OutWriter returns the writer the app writes output to, i.e. the Writer of
+// the nearest app in the lineage which has one, or os.Stdout
+func (c *Context) OutWriter() io.Writer {
+	for _, ctx := range c.Lineage() {
+		if ctx.App != nil && ctx.App.Writer != nil {
+			return ctx.App.Writer
+		}
+	}
+	return os.Stdout
+}
+
+// ErrorWriter returns the writer the app writes errors to, i.e. the ErrWriter
+// of the nearest app in the lineage which has one, or the global ErrWriter
+func (c *Context) ErrorWriter() io.Writer {
+	for _, ctx := range c.Lineage() {
+		if ctx.App != nil && ctx.App.ErrWriter != nil {
+			return ctx.App.ErrWriter
+		}
+	}
+	return ErrWriter
+}
+
+// InReader returns the reader the app reads input from, i.e. the Reader of
+// the nearest app in the lineage which has one, or os.Stdin
+func (c *Context) InReader() io.Reader {
+	for _, ctx := range c.Lineage() {
+		if ctx.App != nil && ctx.App.Reader != nil {
+			return ctx.App.Reader
+		}
+	}
+	return os.Stdin
+}
+
 // Value returns the value of the flag corresponding to `name`
 func (c *Context) Value(name string) interface{} {
 	if fs := c.lookupFlagSet(name); fs != nil {
@@ -116,11 +247,82 @@ func (c *Context) Args() Args {
 	return &ret
 }
 
+// ArgsTerminated returns true if the flags of this context were ended with
+// "--" on the command line, e.g. for "mytool run -- cmd" but not for
+// "mytool run cmd"
+func (c *Context) ArgsTerminated() bool {
+	return c.argsTerminated
+}
+
 // NArg returns the number of the command line arguments.
 func (c *Context) NArg() int {
 	return c.Args().Len()
 }
 
+// setFlag is a flag that was set for a context along with its value
+type setFlag struct {
+	flag  Flag
+	name  string
+	value string
+	// the context the flag was parsed for
+	ctx *Context
+}
+
+// setFlags returns the flags set for this context and its ancestors, from
+// the root down, whether on the command line or through env or file
+func (c *Context) setFlags() []setFlag {
+	var set []setFlag
+	seen := make(map[string]bool)
+
+	lineage := c.Lineage()
+	for i := len(lineage) - 1; i >= 0; i-- {
+		ctx := lineage[i]
+		if ctx.flagSet == nil {
+			continue
+		}
+
+		visited := make(map[string]bool)
+		ctx.flagSet.Visit(func(f *flag.Flag) {
+			visited[f.Name] = true
+		})
+
+		for _, f := range ctx.localFlags() {
+			names := f.Names()
+			if len(names) == 0 || seen[names[0]] {
+				continue
+			}
+
+			// f.IsSet() also holds for flags set by earlier runs
+			isSet := false
+			for _, name := range names {
+				isSet = isSet || visited[name] || ctx.occurrences[name] > 0 || ctx.fromEnv[name]
+			}
+
+			ff := ctx.flagSet.Lookup(names[0])
+			if !isSet || ff == nil {
+				continue
+			}
+
+			seen[names[0]] = true
+			set = append(set, setFlag{flag: f, name: names[0], value: flagValueString(ff.Value), ctx: ctx})
+		}
+	}
+
+	return set
+}
+
+// localFlags returns the flags defined by the command or app this context
+// was created for
+func (c *Context) localFlags() []Flag {
+	if c.Command != nil && c.Command.Name != "" {
+		return c.Command.Flags
+	}
+	if c.App != nil {
+		return c.App.Flags
+	}
+	return nil
+}
+
 func (ctx *Context) lookupFlag(name string) Flag {
 	for _, c := range ctx.Lineage() {
 		if c.Command == nil {
@@ -189,6 +391,128 @@ func (context *Context) checkRequiredFlags(flags []Flag) requiredFlagsErr {
 	return nil
 }
 
+// checkTTYFlags returns an error if a flag which requires a terminal was
+// given on the command line while the app does not read from and write to one
+func (context *Context) checkTTYFlags(flags []Flag) error {
+	for _, f := range flags {
+		if !flagOptions(f).RequireTTY {
+			continue
+		}
+		for _, name := range f.Names() {
+			if context.occurrences[name] > 0 && !context.isTerminal() {
+				return fmt.Errorf("%s%s requires a terminal", prefixFor(name), name)
+			}
+		}
+	}
+	return nil
+}
+
+// shortFlagResolver returns the ShortFlagResolver of the nearest command or
+// app in the lineage which sets one
+func (c *Context) shortFlagResolver() ShortFlagResolverFunc {
+	for _, ctx := range c.Lineage() {
+		if ctx.Command != nil && ctx.Command.ShortFlagResolver != nil {
+			return ctx.Command.ShortFlagResolver
+		}
+		if ctx.App != nil && ctx.App.ShortFlagResolver != nil {
+			return ctx.App.ShortFlagResolver
+		}
+	}
+	return nil
+}
+
+// errorOnDuplicateScalarFlag returns true if any command or app in the
+// lineage sets ErrorOnDuplicateScalarFlag
+func (c *Context) errorOnDuplicateScalarFlag() bool {
+	for _, ctx := range c.Lineage() {
+		if ctx.Command != nil && ctx.Command.ErrorOnDuplicateScalarFlag {
+			return true
+		}
+		if ctx.App != nil && ctx.App.ErrorOnDuplicateScalarFlag {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultTimezone returns the DefaultTimezone of the nearest command or app
+// in the lineage which sets one
+func (c *Context) defaultTimezone() *time.Location {
+	for _, ctx := range c.Lineage() {
+		if ctx.Command != nil && ctx.Command.DefaultTimezone != nil {
+			return ctx.Command.DefaultTimezone
+		}
+		if ctx.App != nil && ctx.App.DefaultTimezone != nil {
+			return ctx.App.DefaultTimezone
+		}
+	}
+	return nil
+}
+
+// flagsFromEnv returns the names of the flags which take a non-empty value
+// from their env vars or file
+func flagsFromEnv(flags []Flag) map[string]bool {
+	fromEnv := make(map[string]bool)
+	for _, f := range flags {
+		if val, _, ok := flagEnvLookup(f); ok && val != "" {
+			for _, name := range f.Names() {
+				fromEnv[name] = true
+			}
+		}
+	}
+	return fromEnv
+}
+
+// warnDeprecatedCommand writes a warning to the ErrorWriter if the command is
+// deprecated
+func (context *Context) warnDeprecatedCommand(cmd *Command) {
+	if cmd.Deprecated != "" {
+		_, _ = fmt.Fprintf(context.ErrorWriter(), "warning: command %q is deprecated: %s\n", cmd.Name, cmd.Deprecated)
+	}
+}
+
+// warnDeprecatedEnvVars writes a warning to the ErrorWriter for each flag
+// which takes its value from one of its DeprecatedEnvVars
+func (context *Context) warnDeprecatedEnvVars(flags []Flag) {
+	for _, f := range flags {
+		if _, envVar, ok := flagEnvLookup(f); ok && envVar != "" {
+			warnDeprecatedEnvVar(context.ErrorWriter(), envVar, flagStringSliceField(f, "EnvVars"))
+		}
+	}
+}
+
+func (context *Context) isTerminal() bool {
+	return isTerminal(context.InReader()) && isTerminal(context.OutWriter())
+}
+
+// isTerminal returns true if the reader or writer is a terminal. Readers and
+// writers which wrap a terminal can tell so with an IsTerminal method.
+func isTerminal(rw interface{}) bool {
+	if t, ok := rw.(interface{ IsTerminal() bool }); ok {
+		return t.IsTerminal()
+	}
+	f, ok := rw.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// envVarName turns a flag name into an environment variable name, e.g.
+// "dry-run" into "DRY_RUN"
+func envVarName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
 func makeFlagNameVisitor(names *[]string) func(*flag.Flag) {
 	return func(f *flag.Flag) {
 		nameParts := strings.Split(f.Name, ",")
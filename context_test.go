@@ -1,9 +1,11 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"flag"
 	"os"
+	"regexp"
 	"sort"
 	"strings"
 	"testing"
@@ -232,6 +234,186 @@ func TestContext_IsSet_fromEnv(t *testing.T) {
 	expect(t, uIsSet, false)
 }
 
+func TestContext_FlagOccurrences(t *testing.T) {
+	cases := []struct {
+		testArgs    []string
+		occurrences int
+		values      []string
+	}{
+		{testArgs: []string{"app"}, occurrences: 0, values: []string{}},
+		{testArgs: []string{"app", "--tag", "a,b"}, occurrences: 1, values: []string{"a,b"}},
+		{testArgs: []string{"app", "--tag", "a", "--tag", "b"}, occurrences: 2, values: []string{"a", "b"}},
+		{testArgs: []string{"app", "-t", "a", "-t=b", "cmd"}, occurrences: 2, values: []string{"a", "b"}},
+	}
+
+	for _, c := range cases {
+		var occurrences int
+		var values []string
+		app := &App{
+			Flags: []Flag{
+				&StringSliceFlag{Name: "tag", Aliases: []string{"t"}},
+			},
+			Commands: []*Command{
+				{
+					Name: "cmd",
+					Action: func(ctx *Context) error {
+						occurrences = ctx.FlagOccurrences("t")
+						values = ctx.StringSlice("tag")
+						return nil
+					},
+				},
+			},
+			Action: func(ctx *Context) error {
+				occurrences = ctx.FlagOccurrences("tag")
+				values = ctx.StringSlice("tag")
+				return nil
+			},
+		}
+
+		err := app.Run(c.testArgs)
+
+		expect(t, err, nil)
+		expect(t, occurrences, c.occurrences)
+		expect(t, values, c.values)
+	}
+}
+
+func TestContext_IsExplicitlySet(t *testing.T) {
+	defer resetEnv(os.Environ())
+	os.Clearenv()
+
+	var explicit, value bool
+	app := &App{
+		Flags: []Flag{
+			&BoolFlag{Name: "debug", EnvVars: []string{"APP_DEBUG"}},
+		},
+		Action: func(ctx *Context) error {
+			explicit = ctx.IsExplicitlySet("debug")
+			value = ctx.Bool("debug")
+			return nil
+		},
+	}
+
+	err := app.Run([]string{"app"})
+	expect(t, err, nil)
+	expect(t, explicit, false)
+	expect(t, value, false)
+
+	err = app.Run([]string{"app", "--debug=false"})
+	expect(t, err, nil)
+	expect(t, explicit, true)
+	expect(t, value, false)
+
+	_ = os.Setenv("APP_DEBUG", "false")
+	err = app.Run([]string{"app"})
+	expect(t, err, nil)
+	expect(t, explicit, true)
+	expect(t, value, false)
+
+	_ = os.Unsetenv("APP_DEBUG")
+	err = app.Run([]string{"app"})
+	expect(t, err, nil)
+	expect(t, explicit, false)
+}
+
+func TestContext_Count(t *testing.T) {
+	var count, occurrences int
+	verbose := &BoolFlag{Name: "verbose", Aliases: []string{"v"}}
+	app := &App{
+		UseShortOptionHandling: true,
+		Flags:                  []Flag{verbose},
+		Commands: []*Command{
+			{
+				Name:  "cmd",
+				Flags: []Flag{verbose},
+				Action: func(ctx *Context) error {
+					count = ctx.Count("verbose")
+					occurrences = ctx.FlagOccurrences("verbose")
+					return nil
+				},
+			},
+		},
+	}
+
+	err := app.Run([]string{"app", "-v", "cmd", "-vv"})
+	expect(t, err, nil)
+	expect(t, count, 3)
+	expect(t, occurrences, 2)
+
+	err = app.Run([]string{"app", "cmd", "-v"})
+	expect(t, err, nil)
+	expect(t, count, 1)
+}
+
+func TestContext_ExportEnv(t *testing.T) {
+	var env []string
+	app := &App{
+		Flags: []Flag{
+			&StringFlag{Name: "config", EnvVars: []string{"APP_CONFIG", "CONFIG"}},
+			&BoolFlag{Name: "dry-run"},
+			&StringFlag{Name: "unset", Value: "default"},
+		},
+		Commands: []*Command{
+			{
+				Name: "cmd",
+				Flags: []Flag{
+					&StringSliceFlag{Name: "tag"},
+					&StringFlag{Name: "password", EnvVars: []string{"APP_PASSWORD"}, FlagOptions: FlagOptions{Sensitive: true}},
+				},
+				Action: func(c *Context) error {
+					env = c.ExportEnv()
+					return nil
+				},
+			},
+		},
+	}
+
+	err := app.Run([]string{"app", "--config", "app.yml", "--dry-run", "cmd", "--tag", "a", "--tag", "b", "--password", "hunter2"})
+
+	expect(t, err, nil)
+	expect(t, env, []string{"APP_CONFIG=app.yml", "DRY_RUN=true", "TAG=a,b"})
+
+	// flags set by an earlier run are not exported
+	defer resetEnv(os.Environ())
+	os.Clearenv()
+	_ = os.Setenv("APP_CONFIG", "env.yml")
+	err = app.Run([]string{"app", "cmd"})
+
+	expect(t, err, nil)
+	expect(t, env, []string{"APP_CONFIG=env.yml"})
+
+	os.Clearenv()
+	err = app.Run([]string{"app", "cmd"})
+
+	expect(t, err, nil)
+	expect(t, len(env), 0)
+}
+
+func TestContext_ExportEnvRedactPattern(t *testing.T) {
+	var env []string
+	app := &App{
+		RedactPattern: regexp.MustCompile(`^ghp_[A-Za-z0-9]+$`),
+		Flags: []Flag{
+			&StringFlag{Name: "token"},
+			&StringFlag{Name: "user"},
+		},
+		Commands: []*Command{
+			{
+				Name: "cmd",
+				Action: func(c *Context) error {
+					env = c.ExportEnv()
+					return nil
+				},
+			},
+		},
+	}
+
+	err := app.Run([]string{"app", "--token", "ghp_abc123", "--user", "jane", "cmd"})
+
+	expect(t, err, nil)
+	expect(t, env, []string{"USER=jane"})
+}
+
 func TestContext_NumFlags(t *testing.T) {
 	set := flag.NewFlagSet("test", 0)
 	set.Bool("myflag", false, "doc")
@@ -595,3 +777,77 @@ func TestCheckRequiredFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestContext_Writers(t *testing.T) {
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	in := strings.NewReader("input")
+
+	var ctx *Context
+	app := &App{
+		Writer:    out,
+		ErrWriter: errOut,
+		Reader:    in,
+		Commands: []*Command{
+			{
+				Name: "plugin",
+				Subcommands: []*Command{
+					{
+						Name: "run",
+						Action: func(c *Context) error {
+							ctx = c
+							return nil
+						},
+					},
+				},
+			},
+		},
+	}
+
+	err := app.Run([]string{"app", "plugin", "run"})
+	expect(t, err, nil)
+	expect(t, ctx.OutWriter(), out)
+	expect(t, ctx.ErrorWriter(), errOut)
+	expect(t, ctx.InReader(), in)
+
+	ctx = NewContext(nil, nil, nil)
+	expect(t, ctx.OutWriter(), os.Stdout)
+	expect(t, ctx.InReader(), os.Stdin)
+}
+
+func TestContext_ArgsTerminated(t *testing.T) {
+	var terminated bool
+	var args []string
+	app := &App{
+		Commands: []*Command{
+			{
+				Name:  "run",
+				Flags: []Flag{&StringFlag{Name: "env"}, &BoolFlag{Name: "quiet"}},
+				Action: func(c *Context) error {
+					terminated = c.ArgsTerminated()
+					args = c.Args().Slice()
+					return nil
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		args       []string
+		terminated bool
+		rest       []string
+	}{
+		{[]string{"mytool", "run", "cmd"}, false, []string{"cmd"}},
+		{[]string{"mytool", "run", "--", "cmd"}, true, []string{"cmd"}},
+		{[]string{"mytool", "run", "--quiet", "--", "-x"}, true, []string{"-x"}},
+		{[]string{"mytool", "run", "--env", "--", "cmd"}, false, []string{"cmd"}},
+		{[]string{"mytool", "run", "cmd", "--", "x"}, false, []string{"cmd", "--", "x"}},
+	}
+
+	for _, c := range cases {
+		err := app.Run(c.args)
+		expect(t, err, nil)
+		expect(t, terminated, c.terminated)
+		expect(t, args, c.rest)
+	}
+}
@@ -114,7 +114,7 @@ func prepareFlags(
 	args := []string{}
 	for _, f := range flags {
 		flag, ok := f.(DocGenerationFlag)
-		if !ok {
+		if !ok || flagOptions(f).EnvOnly {
 			continue
 		}
 		modifiedArg := opener
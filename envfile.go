@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// defaultEnvFile is the file loaded when App.AutoLoadDotEnv is set
+const defaultEnvFile = ".env"
+
+// loadEnvFiles loads the env file of the app, if any, into the environment
+func (a *App) loadEnvFiles() error {
+	if a.EnvFile != "" {
+		return loadEnvFile(a.EnvFile)
+	}
+
+	if a.AutoLoadDotEnv {
+		if _, err := os.Stat(defaultEnvFile); err == nil {
+			return loadEnvFile(defaultEnvFile)
+		}
+	}
+
+	return nil
+}
+
+// loadEnvFile sets the KEY=VALUE entries of a file in the environment. Blank
+// lines and lines starting with # are skipped, values may be quoted and
+// variables which are already set are left alone.
+func loadEnvFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("%s:%d: expected KEY=VALUE", path, i+1)
+		}
+
+		key := strings.TrimSpace(parts[0])
+		value, err := parseEnvFileValue(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return fmt.Errorf("%s:%d: %s", path, i+1, err)
+		}
+
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parseEnvFileValue(value string) (string, error) {
+	if strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'") {
+		return parseQuotedEnvFileValue(value)
+	}
+
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value, nil
+}
+
+// parseQuotedEnvFileValue returns a quoted value up to its first unescaped
+// closing quote, ignoring whatever follows like a comment. Double quoted values
+// only treat \", \\ and \n as escapes, other backslashes are kept as they are.
+func parseQuotedEnvFileValue(value string) (string, error) {
+	quote := value[0]
+	var b strings.Builder
+	for i := 1; i < len(value); i++ {
+		c := value[i]
+		if c == quote {
+			return b.String(), nil
+		}
+		if c == '\\' && quote == '"' && i+1 < len(value) {
+			switch value[i+1] {
+			case '"', '\\':
+				c = value[i+1]
+				i++
+			case 'n':
+				c = '\n'
+				i++
+			}
+		}
+		b.WriteByte(c)
+	}
+	return "", fmt.Errorf("unterminated quoted value %s", value)
+}
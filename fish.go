@@ -116,7 +116,7 @@ func (a *App) prepareFishFlags(flags []Flag, previousCommands []string) []string
 	completions := []string{}
 	for _, f := range flags {
 		flag, ok := f.(DocGenerationFlag)
-		if !ok {
+		if !ok || flagOptions(f).EnvOnly {
 			continue
 		}
 
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"reflect"
 	"regexp"
@@ -35,6 +36,13 @@ var VersionFlag Flag = &BoolFlag{
 	Usage:   "print the version",
 }
 
+// PrintCommandFlag prints the resolved command before it is run, if
+// EnablePrintCommand is set
+var PrintCommandFlag Flag = &BoolFlag{
+	Name:  "print-command",
+	Usage: "print the resolved command before running it",
+}
+
 // HelpFlag prints the help for all commands and subcommands.
 // Set to nil to disable the flag.  The subcommand
 // will still be added unless HideHelp or HideHelpCommand is set to true.
@@ -53,6 +61,13 @@ type Serializer interface {
 	Serialize() string
 }
 
+// RepeatableValue is implemented by flag values which collect every value
+// their flag is given rather than keeping the last one, so that giving the
+// flag more than once is not an error under ErrorOnDuplicateScalarFlag
+type RepeatableValue interface {
+	IsRepeatable() bool
+}
+
 // FlagNamePrefixer converts a full flag name and its placeholder into the help
 // message flag prefix. This is used by the default FlagStringer.
 var FlagNamePrefixer FlagNamePrefixFunc = prefixedNames
@@ -127,10 +142,102 @@ type VisibleFlag interface {
 	IsVisible() bool
 }
 
-func flagSet(name string, flags []Flag) (*flag.FlagSet, error) {
+// SensitiveFlag is an interface that allows to check if a flag value must not
+// be revealed, e.g. in exported environments
+type SensitiveFlag interface {
+	Flag
+
+	// IsSensitive returns true if the flag value must not be revealed,
+	// otherwise false
+	IsSensitive() bool
+}
+
+// FlagOptions are the options shared by all flags, which embed them, e.g.
+//
+//	&StringFlag{Name: "token", FlagOptions: FlagOptions{Sensitive: true}}
+type FlagOptions struct {
+	// Environment variables read after EnvVars, with a warning that they are
+	// deprecated
+	DeprecatedEnvVars []string
+	// Boolean to only read the flag from the environment. Such flags can not
+	// be given on the command line.
+	EnvOnly bool
+	// Boolean to let a value from the environment take precedence over the
+	// one given on the command line
+	EnvOverridesFlag bool
+	// Boolean to make giving the flag on the command line an error when the
+	// app does not read from and write to a terminal
+	RequireTTY bool
+	// Boolean to keep the value out of help, printed commands and exported
+	// environments
+	Sensitive bool
+	// Function to recover from an invalid value, see ParseErrorFunc
+	OnParseError ParseErrorFunc
+}
+
+// IsSensitive returns true if the flag value must not be revealed, otherwise false
+func (o *FlagOptions) IsSensitive() bool {
+	return o.Sensitive
+}
+
+func (o *FlagOptions) options() *FlagOptions {
+	return o
+}
+
+// flagOptions returns the FlagOptions of a flag, which are zero for flags that
+// do not embed them
+func flagOptions(f Flag) FlagOptions {
+	if of, ok := f.(interface{ options() *FlagOptions }); ok {
+		return *of.options()
+	}
+	return FlagOptions{}
+}
+
+// SliceConfig defines the configuration for slice flags
+type SliceConfig struct {
+	// MaxItems is the maximum number of values, or 0 for no limit
+	MaxItems int
+	// DefaultTextMaxItems is the maximum number of default values shown in
+	// help before the rest are summarized, or 0 to show them all
+	DefaultTextMaxItems int
+}
+
+// checkItems returns an error if no value may be added to the given number
+func (c SliceConfig) checkItems(n int) error {
+	if c.MaxItems > 0 && n >= c.MaxItems {
+		return fmt.Errorf("too many values, at most %d are allowed", c.MaxItems)
+	}
+	return nil
+}
+
+// truncateDefaults returns at most DefaultTextMaxItems of the default values
+// shown in help, followed by how many more there are, e.g. "... (+5 more)"
+func (c SliceConfig) truncateDefaults(vals []string) []string {
+	if c.DefaultTextMaxItems <= 0 || len(vals) <= c.DefaultTextMaxItems {
+		return vals
+	}
+	more := fmt.Sprintf("... (+%d more)", len(vals)-c.DefaultTextMaxItems)
+	return append(vals[:c.DefaultTextMaxItems:c.DefaultTextMaxItems], more)
+}
+
+func flagSet(name string, flags []Flag, loc *time.Location) (*flag.FlagSet, error) {
+	if err := checkDuplicateFlagNames(flags); err != nil {
+		return nil, err
+	}
+
 	set := flag.NewFlagSet(name, flag.ContinueOnError)
 
 	for _, f := range flags {
+		// env only flags are applied once the args are parsed, see parseArgs
+		if flagOptions(f).EnvOnly {
+			continue
+		}
+		if tf, ok := f.(*TimestampFlag); ok {
+			if err := tf.applyInTimezone(set, loc); err != nil {
+				return nil, err
+			}
+			continue
+		}
 		if err := f.Apply(set); err != nil {
 			return nil, err
 		}
@@ -139,6 +246,24 @@ func flagSet(name string, flags []Flag) (*flag.FlagSet, error) {
 	return set, nil
 }
 
+// checkDuplicateFlagNames ensures no two flags share a name or alias, which
+// would otherwise make flag.FlagSet panic while applying them
+func checkDuplicateFlagNames(flags []Flag) error {
+	seen := make(map[string]bool)
+	for _, f := range flags {
+		for _, name := range f.Names() {
+			if name == "" {
+				continue
+			}
+			if seen[name] {
+				return fmt.Errorf("duplicate flag name %q", name)
+			}
+			seen[name] = true
+		}
+	}
+	return nil
+}
+
 func copyFlag(name string, ff *flag.Flag, set *flag.FlagSet) {
 	switch ff.Value.(type) {
 	case Serializer:
@@ -181,6 +306,72 @@ func normalizeFlags(flags []Flag, set *flag.FlagSet) error {
 	return nil
 }
 
+func cloneFlags(flags []Flag) []Flag {
+	if flags == nil {
+		return nil
+	}
+
+	cloned := make([]Flag, len(flags))
+	for i, f := range flags {
+		cloned[i] = cloneFlag(f)
+	}
+	return cloned
+}
+
+// cloneFlag copies a flag struct along with its Value, and resets the state of
+// parsing it. The built-in flags, which are looked up by identity, and flags
+// which are not pointers to structs are returned as they are. The Value of a
+// GenericFlag is shared, as its type is not known.
+func cloneFlag(f Flag) Flag {
+	if f == HelpFlag || f == VersionFlag || f == BashCompletionFlag || f == PrintCommandFlag {
+		return f
+	}
+
+	fv := reflect.ValueOf(f)
+	if fv.Kind() != reflect.Ptr || fv.Elem().Kind() != reflect.Struct {
+		return f
+	}
+
+	nv := reflect.New(fv.Elem().Type())
+	nv.Elem().Set(fv.Elem())
+
+	if hasBeenSet := nv.Elem().FieldByName("HasBeenSet"); hasBeenSet.IsValid() && hasBeenSet.CanSet() {
+		hasBeenSet.SetBool(false)
+	}
+
+	if value := nv.Elem().FieldByName("Value"); value.IsValid() && value.CanSet() {
+		switch v := value.Interface().(type) {
+		case *StringSlice:
+			if v != nil {
+				value.Set(reflect.ValueOf(v.clone()))
+			}
+		case *IntSlice:
+			if v != nil {
+				value.Set(reflect.ValueOf(v.clone()))
+			}
+		case *Int64Slice:
+			if v != nil {
+				value.Set(reflect.ValueOf(v.clone()))
+			}
+		case *Float64Slice:
+			if v != nil {
+				value.Set(reflect.ValueOf(v.clone()))
+			}
+		case *StringMap:
+			if v != nil {
+				value.Set(reflect.ValueOf(v.clone()))
+			}
+		case *Timestamp:
+			if v != nil {
+				t := *v
+				value.Set(reflect.ValueOf(&t))
+			}
+		}
+	}
+
+	return nv.Interface().(Flag)
+}
+
 func visibleFlags(fl []Flag) []Flag {
 	var visible []Flag
 	for _, f := range fl {
@@ -236,23 +427,45 @@ func prefixedNames(names []string, placeholder string) string {
 	return prefixed
 }
 
+// prefixedBoolNames is like prefixedNames, but shows the optional explicit
+// value of a bool flag after each name
+func prefixedBoolNames(names []string) string {
+	var prefixed string
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+
+		prefixed += prefixFor(name) + name + "[=true|false]"
+		if i < len(names)-1 {
+			prefixed += ", "
+		}
+	}
+	return prefixed
+}
+
 func withEnvHint(envVars []string, str string) string {
 	envText := ""
 	if envVars != nil && len(envVars) > 0 {
-		prefix := "$"
-		suffix := ""
-		sep := ", $"
-		if runtime.GOOS == "windows" {
-			prefix = "%"
-			suffix = "%"
-			sep = "%, %"
-		}
-
-		envText = fmt.Sprintf(" [%s%s%s]", prefix, strings.Join(envVars, sep), suffix)
+		envText = fmt.Sprintf(" [%s]", envVarNames(envVars))
 	}
 	return str + envText
 }
 
+// envVarNames lists environment variables the way the shell of the platform
+// expands them
+func envVarNames(envVars []string) string {
+	prefix := "$"
+	suffix := ""
+	sep := ", $"
+	if runtime.GOOS == "windows" {
+		prefix = "%"
+		suffix = "%"
+		sep = "%, %"
+	}
+	return prefix + strings.Join(envVars, sep) + suffix
+}
+
 func flagNames(name string, aliases []string) []string {
 	var ret []string
 
@@ -278,6 +491,15 @@ func flagStringSliceField(f Flag, name string) []string {
 	return []string{}
 }
 
+// flagCompleteValuesFunc returns the CompleteValues function of flags which
+// have one, i.e. those taking free-form values
+func flagCompleteValuesFunc(f Flag) CompleteValuesFunc {
+	if cf, ok := f.(interface{ completeValuesFunc() CompleteValuesFunc }); ok {
+		return cf.completeValuesFunc()
+	}
+	return nil
+}
+
 func withFileHint(filePath, str string) string {
 	fileText := ""
 	if filePath != "" {
@@ -301,6 +523,10 @@ func formatDefault(format string) string {
 func stringifyFlag(f Flag) string {
 	fv := flagValue(f)
 
+	if flagOptions(f).EnvOnly {
+		return stringifyEnvOnlyFlag(f)
+	}
+
 	switch f := f.(type) {
 	case *IntSliceFlag:
 		return withEnvHint(flagStringSliceField(f, "EnvVars"),
@@ -314,6 +540,9 @@ func stringifyFlag(f Flag) string {
 	case *StringSliceFlag:
 		return withEnvHint(flagStringSliceField(f, "EnvVars"),
 			stringifyStringSliceFlag(f))
+	case *StringMapFlag:
+		return withEnvHint(flagStringSliceField(f, "EnvVars"),
+			stringifyStringMapFlag(f))
 	}
 
 	placeholder, usage := unquoteUsage(fv.FieldByName("Usage").String())
@@ -346,8 +575,13 @@ func stringifyFlag(f Flag) string {
 
 	usageWithDefault := strings.TrimSpace(usage + defaultValueString)
 
+	names := prefixedNames(f.Names(), placeholder)
+	if bf, ok := f.(*BoolFlag); ok && bf.Config.ShowValueInHelp {
+		names = prefixedBoolNames(f.Names())
+	}
+
 	return withEnvHint(flagStringSliceField(f, "EnvVars"),
-		fmt.Sprintf("%s\t%s", prefixedNames(f.Names(), placeholder), usageWithDefault))
+		fmt.Sprintf("%s\t%s", names, usageWithDefault))
 }
 
 func stringifyIntSliceFlag(f *IntSliceFlag) string {
@@ -358,7 +592,7 @@ func stringifyIntSliceFlag(f *IntSliceFlag) string {
 		}
 	}
 
-	return stringifySliceFlag(f.Usage, f.Names(), defaultVals)
+	return stringifySliceFlag(f.Usage, f.Names(), f.Config.truncateDefaults(defaultVals))
 }
 
 func stringifyInt64SliceFlag(f *Int64SliceFlag) string {
@@ -369,7 +603,7 @@ func stringifyInt64SliceFlag(f *Int64SliceFlag) string {
 		}
 	}
 
-	return stringifySliceFlag(f.Usage, f.Names(), defaultVals)
+	return stringifySliceFlag(f.Usage, f.Names(), f.Config.truncateDefaults(defaultVals))
 }
 
 func stringifyFloat64SliceFlag(f *Float64SliceFlag) string {
@@ -381,7 +615,7 @@ func stringifyFloat64SliceFlag(f *Float64SliceFlag) string {
 		}
 	}
 
-	return stringifySliceFlag(f.Usage, f.Names(), defaultVals)
+	return stringifySliceFlag(f.Usage, f.Names(), f.Config.truncateDefaults(defaultVals))
 }
 
 func stringifyStringSliceFlag(f *StringSliceFlag) string {
@@ -394,9 +628,28 @@ func stringifyStringSliceFlag(f *StringSliceFlag) string {
 		}
 	}
 
+	return stringifySliceFlag(f.Usage, f.Names(), f.Config.truncateDefaults(defaultVals))
+}
+
+func stringifyStringMapFlag(f *StringMapFlag) string {
+	var defaultVals []string
+	if f.Value != nil {
+		for _, k := range f.Value.keys() {
+			defaultVals = append(defaultVals, strconv.Quote(k+f.Config.keySeparator()+f.Value.m[k]))
+		}
+	}
+
 	return stringifySliceFlag(f.Usage, f.Names(), defaultVals)
 }
 
+// stringifyEnvOnlyFlag shows a flag which can not be given on the command line
+// by its environment variables instead of its names
+func stringifyEnvOnlyFlag(f Flag) string {
+	_, usage := unquoteUsage(flagValue(f).FieldByName("Usage").String())
+	return fmt.Sprintf("%s\t%s", envVarNames(flagStringSliceField(f, "EnvVars")),
+		strings.TrimSpace(usage+" (env only)"))
+}
+
 func stringifySliceFlag(usage string, names, defaultVals []string) string {
 	placeholder, usage := unquoteUsage(usage)
 	if placeholder == "" {
@@ -416,6 +669,78 @@ func stringifySliceFlag(usage string, names, defaultVals []string) string {
 	return fmt.Sprintf("%s\t%s%s", prefixedNames(names, placeholder), usageWithDefault, multiInputString)
 }
 
+// hasDefaultValue returns true if the flag has a non-empty default Value
+func hasDefaultValue(fl Flag) bool {
+	val := flagValue(fl).FieldByName("Value")
+	if !val.IsValid() || isZeroValue(val) {
+		return false
+	}
+
+	switch v := val.Interface().(type) {
+	case *StringSlice:
+		return len(v.Value()) > 0
+	case *StringMap:
+		return len(v.Value()) > 0
+	case *IntSlice:
+		return len(v.Value()) > 0
+	case *Int64Slice:
+		return len(v.Value()) > 0
+	case *Float64Slice:
+		return len(v.Value()) > 0
+	case *Timestamp:
+		return v.Value() != nil
+	}
+	return true
+}
+
+// isZeroValue reports whether v is the zero value of its type, like
+// reflect.Value.IsZero which is not available before Go 1.13
+func isZeroValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	if v.Type().Comparable() {
+		return v.Interface() == reflect.Zero(v.Type()).Interface()
+	}
+	return false
+}
+
+func isSensitive(fl Flag) bool {
+	sf, ok := fl.(SensitiveFlag)
+	return ok && sf.IsSensitive()
+}
+
+// flagValueString returns the value of a parsed flag the way it would be given
+// on the command line, joining slice values with commas
+func flagValueString(value flag.Value) string {
+	var vals []string
+	switch v := value.(type) {
+	case *StringSlice:
+		vals = v.Value()
+	case *IntSlice:
+		for _, i := range v.Value() {
+			vals = append(vals, strconv.Itoa(i))
+		}
+	case *Int64Slice:
+		for _, i := range v.Value() {
+			vals = append(vals, strconv.FormatInt(i, 10))
+		}
+	case *Float64Slice:
+		for _, f := range v.Value() {
+			vals = append(vals, strconv.FormatFloat(f, 'g', -1, 64))
+		}
+	case *Timestamp:
+		if v.Value() == nil {
+			return ""
+		}
+		return v.Value().Format(v.layout)
+	default:
+		return value.String()
+	}
+	return strings.Join(vals, ",")
+}
+
 func hasFlag(flags []Flag, fl Flag) bool {
 	for _, existing := range flags {
 		if fl == existing {
@@ -427,16 +752,58 @@ func hasFlag(flags []Flag, fl Flag) bool {
 }
 
 func flagFromEnvOrFile(envVars []string, filePath string) (val string, ok bool) {
+	return flagFromEnvOrDeprecatedEnvOrFile(envVars, nil, filePath)
+}
+
+// flagFromEnvOrDeprecatedEnvOrFile looks the value up like flagFromEnvOrFile,
+// falling back to the deprecatedEnvVars before the file. Using a deprecated
+// variable is warned about once per run, see Context.warnDeprecatedEnvVars.
+func flagFromEnvOrDeprecatedEnvOrFile(envVars, deprecatedEnvVars []string, filePath string) (val string, ok bool) {
+	val, _, ok = lookupEnvOrDeprecatedEnvOrFile(envVars, deprecatedEnvVars, filePath)
+	return val, ok
+}
+
+// lookupEnvOrDeprecatedEnvOrFile looks the value up without warning, also
+// returning the deprecated variable it came from, if any
+func lookupEnvOrDeprecatedEnvOrFile(envVars, deprecatedEnvVars []string, filePath string) (val, deprecatedEnvVar string, ok bool) {
 	for _, envVar := range envVars {
 		envVar = strings.TrimSpace(envVar)
 		if val, ok := syscall.Getenv(envVar); ok {
-			return val, true
+			return val, "", true
+		}
+	}
+	for _, envVar := range deprecatedEnvVars {
+		envVar = strings.TrimSpace(envVar)
+		if val, ok := syscall.Getenv(envVar); ok {
+			return val, envVar, true
 		}
 	}
 	for _, fileVar := range strings.Split(filePath, ",") {
 		if data, err := ioutil.ReadFile(fileVar); err == nil {
-			return string(data), true
+			return string(data), "", true
+		}
+	}
+	return "", "", false
+}
+
+// flagEnvLookup looks the value of f up from its environment variables or
+// files the way its Apply does
+func flagEnvLookup(f Flag) (val, deprecatedEnvVar string, ok bool) {
+	filePath := ""
+	if fv := flagValue(f); fv.Kind() == reflect.Struct {
+		if field := fv.FieldByName("FilePath"); field.IsValid() {
+			filePath = field.String()
 		}
 	}
-	return "", false
+	return lookupEnvOrDeprecatedEnvOrFile(flagStringSliceField(f, "EnvVars"),
+		flagOptions(f).DeprecatedEnvVars, filePath)
+}
+
+func warnDeprecatedEnvVar(w io.Writer, envVar string, envVars []string) {
+	if len(envVars) == 0 {
+		_, _ = fmt.Fprintf(w, "warning: environment variable %q is deprecated\n", envVar)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "warning: environment variable %q is deprecated, use %q instead\n",
+		envVar, strings.TrimSpace(envVars[0]))
 }
@@ -6,6 +6,13 @@ import (
 	"strconv"
 )
 
+// BoolConfig defines the configuration for bool flags
+type BoolConfig struct {
+	// ShowValueInHelp renders the explicit value syntax in help, e.g.
+	// "--color[=true|false]" instead of "--color"
+	ShowValueInHelp bool
+}
+
 // BoolFlag is a flag with type bool
 type BoolFlag struct {
 	Name        string
@@ -19,6 +26,9 @@ type BoolFlag struct {
 	DefaultText string
 	Destination *bool
 	HasBeenSet  bool
+	Config      BoolConfig
+
+	FlagOptions
 }
 
 // IsSet returns whether or not the flag has been set through env or file
@@ -65,7 +75,7 @@ func (f *BoolFlag) IsVisible() bool {
 
 // Apply populates the flag given the flag set and environment
 func (f *BoolFlag) Apply(set *flag.FlagSet) error {
-	if val, ok := flagFromEnvOrFile(f.EnvVars, f.FilePath); ok {
+	if val, ok := flagFromEnvOrDeprecatedEnvOrFile(f.EnvVars, f.DeprecatedEnvVars, f.FilePath); ok {
 		if val != "" {
 			valBool, err := strconv.ParseBool(val)
 
@@ -6,6 +6,50 @@ import (
 	"time"
 )
 
+// DurationConfig defines the configuration for duration flags
+type DurationConfig struct {
+	// Keywords maps special values, e.g. "none", to the duration they stand
+	// for. Any other value is parsed with time.ParseDuration.
+	Keywords map[string]time.Duration
+}
+
+func (c DurationConfig) parse(value string) (time.Duration, error) {
+	if d, ok := c.Keywords[value]; ok {
+		return d, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// durationValue is a flag.Value for durations which understands the keywords
+// of a DurationConfig
+type durationValue struct {
+	duration *time.Duration
+	config   DurationConfig
+}
+
+// Set parses the value into a duration
+func (d *durationValue) Set(value string) error {
+	parsed, err := d.config.parse(value)
+	if err != nil {
+		return err
+	}
+	*d.duration = parsed
+	return nil
+}
+
+// String returns a readable representation of this value
+func (d *durationValue) String() string {
+	if d.duration == nil {
+		return ""
+	}
+	return d.duration.String()
+}
+
+// Get returns the duration set by this flag
+func (d *durationValue) Get() interface{} {
+	return *d.duration
+}
+
 // DurationFlag is a flag with type time.Duration (see https://golang.org/pkg/time/#ParseDuration)
 type DurationFlag struct {
 	Name        string
@@ -19,6 +63,9 @@ type DurationFlag struct {
 	DefaultText string
 	Destination *time.Duration
 	HasBeenSet  bool
+	Config      DurationConfig
+
+	FlagOptions
 }
 
 // IsSet returns whether or not the flag has been set through env or file
@@ -65,9 +112,9 @@ func (f *DurationFlag) IsVisible() bool {
 
 // Apply populates the flag given the flag set and environment
 func (f *DurationFlag) Apply(set *flag.FlagSet) error {
-	if val, ok := flagFromEnvOrFile(f.EnvVars, f.FilePath); ok {
+	if val, ok := flagFromEnvOrDeprecatedEnvOrFile(f.EnvVars, f.DeprecatedEnvVars, f.FilePath); ok {
 		if val != "" {
-			valDuration, err := time.ParseDuration(val)
+			valDuration, err := f.Config.parse(val)
 
 			if err != nil {
 				return fmt.Errorf("could not parse %q as duration value for flag %s: %s", val, f.Name, err)
@@ -79,6 +126,15 @@ func (f *DurationFlag) Apply(set *flag.FlagSet) error {
 	}
 
 	for _, name := range f.Names() {
+		if len(f.Config.Keywords) > 0 {
+			destination := f.Destination
+			if destination == nil {
+				destination = new(time.Duration)
+			}
+			*destination = f.Value
+			set.Var(&durationValue{duration: destination, config: f.Config}, name, f.Usage)
+			continue
+		}
 		if f.Destination != nil {
 			set.DurationVar(f.Destination, name, f.Value, f.Usage)
 			continue
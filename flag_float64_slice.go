@@ -12,6 +12,7 @@ import (
 type Float64Slice struct {
 	slice      []float64
 	hasBeenSet bool
+	config     SliceConfig
 }
 
 // NewFloat64Slice makes a *Float64Slice with default values
@@ -24,6 +25,7 @@ func (f *Float64Slice) clone() *Float64Slice {
 	n := &Float64Slice{
 		slice:      make([]float64, len(f.slice)),
 		hasBeenSet: f.hasBeenSet,
+		config:     f.config,
 	}
 	copy(n.slice, f.slice)
 	return n
@@ -48,6 +50,10 @@ func (f *Float64Slice) Set(value string) error {
 		return err
 	}
 
+	if err := f.config.checkItems(len(f.slice)); err != nil {
+		return err
+	}
+
 	f.slice = append(f.slice, tmp)
 	return nil
 }
@@ -73,6 +79,11 @@ func (f *Float64Slice) Get() interface{} {
 	return *f
 }
 
+// IsRepeatable allows Float64Slice to fulfill RepeatableValue
+func (f *Float64Slice) IsRepeatable() bool {
+	return true
+}
+
 // Float64SliceFlag is a flag with type *Float64Slice
 type Float64SliceFlag struct {
 	Name        string
@@ -85,6 +96,9 @@ type Float64SliceFlag struct {
 	Value       *Float64Slice
 	DefaultText string
 	HasBeenSet  bool
+	Config      SliceConfig
+
+	FlagOptions
 }
 
 // IsSet returns whether or not the flag has been set through env or file
@@ -134,9 +148,9 @@ func (f *Float64SliceFlag) IsVisible() bool {
 
 // Apply populates the flag given the flag set and environment
 func (f *Float64SliceFlag) Apply(set *flag.FlagSet) error {
-	if val, ok := flagFromEnvOrFile(f.EnvVars, f.FilePath); ok {
+	if val, ok := flagFromEnvOrDeprecatedEnvOrFile(f.EnvVars, f.DeprecatedEnvVars, f.FilePath); ok {
 		if val != "" {
-			f.Value = &Float64Slice{}
+			f.Value = &Float64Slice{config: f.Config}
 
 			for _, s := range strings.Split(val, ",") {
 				if err := f.Value.Set(strings.TrimSpace(s)); err != nil {
@@ -151,6 +165,7 @@ func (f *Float64SliceFlag) Apply(set *flag.FlagSet) error {
 	if f.Value == nil {
 		f.Value = &Float64Slice{}
 	}
+	f.Value.config = f.Config
 	copyValue := f.Value.clone()
 	for _, name := range f.Names() {
 		set.Var(copyValue, name, f.Usage)
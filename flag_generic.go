@@ -13,17 +13,20 @@ type Generic interface {
 
 // GenericFlag is a flag with type Generic
 type GenericFlag struct {
-	Name        string
-	Aliases     []string
-	Usage       string
-	EnvVars     []string
-	FilePath    string
-	Required    bool
-	Hidden      bool
-	TakesFile   bool
-	Value       Generic
-	DefaultText string
-	HasBeenSet  bool
+	Name           string
+	Aliases        []string
+	Usage          string
+	EnvVars        []string
+	FilePath       string
+	Required       bool
+	Hidden         bool
+	CompleteValues CompleteValuesFunc
+	TakesFile      bool
+	Value          Generic
+	DefaultText    string
+	HasBeenSet     bool
+
+	FlagOptions
 }
 
 // IsSet returns whether or not the flag has been set through env or file
@@ -71,10 +74,15 @@ func (f *GenericFlag) IsVisible() bool {
 	return !f.Hidden
 }
 
+// completeValuesFunc returns the CompleteValues function of the flag
+func (f *GenericFlag) completeValuesFunc() CompleteValuesFunc {
+	return f.CompleteValues
+}
+
 // Apply takes the flagset and calls Set on the generic flag with the value
 // provided by the user for parsing by the flag
 func (f GenericFlag) Apply(set *flag.FlagSet) error {
-	if val, ok := flagFromEnvOrFile(f.EnvVars, f.FilePath); ok {
+	if val, ok := flagFromEnvOrDeprecatedEnvOrFile(f.EnvVars, f.DeprecatedEnvVars, f.FilePath); ok {
 		if val != "" {
 			if err := f.Value.Set(val); err != nil {
 				return fmt.Errorf("could not parse %q as value for flag %s: %s", val, f.Name, err)
@@ -19,6 +19,8 @@ type Int64Flag struct {
 	DefaultText string
 	Destination *int64
 	HasBeenSet  bool
+
+	FlagOptions
 }
 
 // IsSet returns whether or not the flag has been set through env or file
@@ -65,7 +67,7 @@ func (f *Int64Flag) IsVisible() bool {
 
 // Apply populates the flag given the flag set and environment
 func (f *Int64Flag) Apply(set *flag.FlagSet) error {
-	if val, ok := flagFromEnvOrFile(f.EnvVars, f.FilePath); ok {
+	if val, ok := flagFromEnvOrDeprecatedEnvOrFile(f.EnvVars, f.DeprecatedEnvVars, f.FilePath); ok {
 		if val != "" {
 			valInt, err := strconv.ParseInt(val, 0, 64)
 
@@ -12,6 +12,7 @@ import (
 type Int64Slice struct {
 	slice      []int64
 	hasBeenSet bool
+	config     SliceConfig
 }
 
 // NewInt64Slice makes an *Int64Slice with default values
@@ -24,6 +25,7 @@ func (i *Int64Slice) clone() *Int64Slice {
 	n := &Int64Slice{
 		slice:      make([]int64, len(i.slice)),
 		hasBeenSet: i.hasBeenSet,
+		config:     i.config,
 	}
 	copy(n.slice, i.slice)
 	return n
@@ -48,6 +50,10 @@ func (i *Int64Slice) Set(value string) error {
 		return err
 	}
 
+	if err := i.config.checkItems(len(i.slice)); err != nil {
+		return err
+	}
+
 	i.slice = append(i.slice, tmp)
 
 	return nil
@@ -74,6 +80,11 @@ func (i *Int64Slice) Get() interface{} {
 	return *i
 }
 
+// IsRepeatable allows Int64Slice to fulfill RepeatableValue
+func (i *Int64Slice) IsRepeatable() bool {
+	return true
+}
+
 // Int64SliceFlag is a flag with type *Int64Slice
 type Int64SliceFlag struct {
 	Name        string
@@ -86,6 +97,9 @@ type Int64SliceFlag struct {
 	Value       *Int64Slice
 	DefaultText string
 	HasBeenSet  bool
+	Config      SliceConfig
+
+	FlagOptions
 }
 
 // IsSet returns whether or not the flag has been set through env or file
@@ -135,8 +149,8 @@ func (f *Int64SliceFlag) IsVisible() bool {
 
 // Apply populates the flag given the flag set and environment
 func (f *Int64SliceFlag) Apply(set *flag.FlagSet) error {
-	if val, ok := flagFromEnvOrFile(f.EnvVars, f.FilePath); ok {
-		f.Value = &Int64Slice{}
+	if val, ok := flagFromEnvOrDeprecatedEnvOrFile(f.EnvVars, f.DeprecatedEnvVars, f.FilePath); ok {
+		f.Value = &Int64Slice{config: f.Config}
 
 		for _, s := range strings.Split(val, ",") {
 			if err := f.Value.Set(strings.TrimSpace(s)); err != nil {
@@ -150,6 +164,7 @@ func (f *Int64SliceFlag) Apply(set *flag.FlagSet) error {
 	if f.Value == nil {
 		f.Value = &Int64Slice{}
 	}
+	f.Value.config = f.Config
 	copyValue := f.Value.clone()
 	for _, name := range f.Names() {
 		set.Var(copyValue, name, f.Usage)
@@ -12,6 +12,7 @@ import (
 type IntSlice struct {
 	slice      []int
 	hasBeenSet bool
+	config     SliceConfig
 }
 
 // NewIntSlice makes an *IntSlice with default values
@@ -24,6 +25,7 @@ func (i *IntSlice) clone() *IntSlice {
 	n := &IntSlice{
 		slice:      make([]int, len(i.slice)),
 		hasBeenSet: i.hasBeenSet,
+		config:     i.config,
 	}
 	copy(n.slice, i.slice)
 	return n
@@ -59,6 +61,10 @@ func (i *IntSlice) Set(value string) error {
 		return err
 	}
 
+	if err := i.config.checkItems(len(i.slice)); err != nil {
+		return err
+	}
+
 	i.slice = append(i.slice, int(tmp))
 
 	return nil
@@ -85,6 +91,11 @@ func (i *IntSlice) Get() interface{} {
 	return *i
 }
 
+// IsRepeatable allows IntSlice to fulfill RepeatableValue
+func (i *IntSlice) IsRepeatable() bool {
+	return true
+}
+
 // IntSliceFlag is a flag with type *IntSlice
 type IntSliceFlag struct {
 	Name        string
@@ -97,6 +108,9 @@ type IntSliceFlag struct {
 	Value       *IntSlice
 	DefaultText string
 	HasBeenSet  bool
+	Config      SliceConfig
+
+	FlagOptions
 }
 
 // IsSet returns whether or not the flag has been set through env or file
@@ -146,8 +160,8 @@ func (f *IntSliceFlag) IsVisible() bool {
 
 // Apply populates the flag given the flag set and environment
 func (f *IntSliceFlag) Apply(set *flag.FlagSet) error {
-	if val, ok := flagFromEnvOrFile(f.EnvVars, f.FilePath); ok {
-		f.Value = &IntSlice{}
+	if val, ok := flagFromEnvOrDeprecatedEnvOrFile(f.EnvVars, f.DeprecatedEnvVars, f.FilePath); ok {
+		f.Value = &IntSlice{config: f.Config}
 
 		for _, s := range strings.Split(val, ",") {
 			if err := f.Value.Set(strings.TrimSpace(s)); err != nil {
@@ -161,6 +175,7 @@ func (f *IntSliceFlag) Apply(set *flag.FlagSet) error {
 	if f.Value == nil {
 		f.Value = &IntSlice{}
 	}
+	f.Value.config = f.Config
 	copyValue := f.Value.clone()
 	for _, name := range f.Names() {
 		set.Var(copyValue, name, f.Usage)
@@ -3,18 +3,21 @@ package cli
 import "flag"
 
 type PathFlag struct {
-	Name        string
-	Aliases     []string
-	Usage       string
-	EnvVars     []string
-	FilePath    string
-	Required    bool
-	Hidden      bool
-	TakesFile   bool
-	Value       string
-	DefaultText string
-	Destination *string
-	HasBeenSet  bool
+	Name           string
+	Aliases        []string
+	Usage          string
+	EnvVars        []string
+	FilePath       string
+	Required       bool
+	Hidden         bool
+	CompleteValues CompleteValuesFunc
+	TakesFile      bool
+	Value          string
+	DefaultText    string
+	Destination    *string
+	HasBeenSet     bool
+
+	FlagOptions
 }
 
 // IsSet returns whether or not the flag has been set through env or file
@@ -59,9 +62,14 @@ func (f *PathFlag) IsVisible() bool {
 	return !f.Hidden
 }
 
+// completeValuesFunc returns the CompleteValues function of the flag
+func (f *PathFlag) completeValuesFunc() CompleteValuesFunc {
+	return f.CompleteValues
+}
+
 // Apply populates the flag given the flag set and environment
 func (f *PathFlag) Apply(set *flag.FlagSet) error {
-	if val, ok := flagFromEnvOrFile(f.EnvVars, f.FilePath); ok {
+	if val, ok := flagFromEnvOrDeprecatedEnvOrFile(f.EnvVars, f.DeprecatedEnvVars, f.FilePath); ok {
 		f.Value = val
 		f.HasBeenSet = true
 	}
@@ -1,21 +1,73 @@
 package cli
 
-import "flag"
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// StringConfig defines the configuration for string flags
+type StringConfig struct {
+	// RejectSeparator makes values containing the "," which separates the
+	// values of slice flags an error, as several values may have been meant
+	RejectSeparator bool
+}
+
+// check returns an error if the value is not allowed for the named flag. The
+// error does not repeat the value, as the flag package adds it.
+func (c StringConfig) check(name, value string) error {
+	if c.RejectSeparator && strings.Contains(value, ",") {
+		return fmt.Errorf("must not contain \",\", %s%s takes a single value", prefixFor(name), name)
+	}
+	return nil
+}
+
+// checkedString is the value of a string flag which checks the values it is
+// set to against the StringConfig of the flag
+type checkedString struct {
+	destination *string
+	// the name of the flag, rather than the alias it is set under
+	name   string
+	config StringConfig
+}
+
+func (s *checkedString) Set(value string) error {
+	if err := s.config.check(s.name, value); err != nil {
+		return err
+	}
+	*s.destination = value
+	return nil
+}
+
+func (s *checkedString) String() string {
+	if s.destination == nil {
+		return ""
+	}
+	return *s.destination
+}
+
+func (s *checkedString) Get() interface{} {
+	return *s.destination
+}
 
 // StringFlag is a flag with type string
 type StringFlag struct {
-	Name        string
-	Aliases     []string
-	Usage       string
-	EnvVars     []string
-	FilePath    string
-	Required    bool
-	Hidden      bool
-	TakesFile   bool
-	Value       string
-	DefaultText string
-	Destination *string
-	HasBeenSet  bool
+	Name           string
+	Aliases        []string
+	Usage          string
+	EnvVars        []string
+	FilePath       string
+	Required       bool
+	Hidden         bool
+	CompleteValues CompleteValuesFunc
+	TakesFile      bool
+	Value          string
+	DefaultText    string
+	Destination    *string
+	HasBeenSet     bool
+	Config         StringConfig
+
+	FlagOptions
 }
 
 // IsSet returns whether or not the flag has been set through env or file
@@ -60,13 +112,34 @@ func (f *StringFlag) IsVisible() bool {
 	return !f.Hidden
 }
 
+// completeValuesFunc returns the CompleteValues function of the flag
+func (f *StringFlag) completeValuesFunc() CompleteValuesFunc {
+	return f.CompleteValues
+}
+
 // Apply populates the flag given the flag set and environment
 func (f *StringFlag) Apply(set *flag.FlagSet) error {
-	if val, ok := flagFromEnvOrFile(f.EnvVars, f.FilePath); ok {
+	if val, ok := flagFromEnvOrDeprecatedEnvOrFile(f.EnvVars, f.DeprecatedEnvVars, f.FilePath); ok {
+		if err := f.Config.check(f.Name, val); err != nil {
+			return fmt.Errorf("could not parse %q as string value for flag %s: %s", val, f.Name, err)
+		}
 		f.Value = val
 		f.HasBeenSet = true
 	}
 
+	if f.Config.RejectSeparator {
+		destination := f.Destination
+		if destination == nil {
+			destination = new(string)
+		}
+		*destination = f.Value
+
+		for _, name := range f.Names() {
+			set.Var(&checkedString{destination: destination, name: f.Name, config: f.Config}, name, f.Usage)
+		}
+		return nil
+	}
+
 	for _, name := range f.Names() {
 		if f.Destination != nil {
 			set.StringVar(f.Destination, name, f.Value, f.Usage)
@@ -0,0 +1,264 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MapConfig defines the configuration for map flags
+type MapConfig struct {
+	// PairSeparator separates the entries of a value, defaults to ","
+	PairSeparator string
+	// KeySeparator separates the key of an entry from its value, defaults
+	// to "="
+	KeySeparator string
+	// AllowedKeys restricts the keys which may be given, if it is not empty
+	AllowedKeys []string
+	// MaxEntries is the maximum number of entries, or 0 for no limit
+	MaxEntries int
+}
+
+func (c MapConfig) pairSeparator() string {
+	if c.PairSeparator == "" {
+		return ","
+	}
+	return c.PairSeparator
+}
+
+func (c MapConfig) keySeparator() string {
+	if c.KeySeparator == "" {
+		return "="
+	}
+	return c.KeySeparator
+}
+
+func (c MapConfig) allowsKey(key string) bool {
+	if len(c.AllowedKeys) == 0 {
+		return true
+	}
+	for _, allowed := range c.AllowedKeys {
+		if key == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// StringMap wraps a map[string]string to satisfy flag.Value
+type StringMap struct {
+	m          map[string]string
+	hasBeenSet bool
+	config     MapConfig
+	// name of the flag, for errors
+	name string
+}
+
+// NewStringMap creates a *StringMap with default values
+func NewStringMap(defaults map[string]string) *StringMap {
+	m := make(map[string]string, len(defaults))
+	for k, v := range defaults {
+		m[k] = v
+	}
+	return &StringMap{m: m}
+}
+
+// clone allocate a copy of self object
+func (s *StringMap) clone() *StringMap {
+	n := NewStringMap(s.m)
+	n.hasBeenSet = s.hasBeenSet
+	n.config = s.config
+	n.name = s.name
+	return n
+}
+
+// Set adds the key/value entries of the string value to the map
+func (s *StringMap) Set(value string) error {
+	if !s.hasBeenSet {
+		s.m = map[string]string{}
+		s.hasBeenSet = true
+	}
+
+	for _, pair := range strings.Split(value, s.config.pairSeparator()) {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, s.config.keySeparator(), 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("expected %q in %q", s.config.keySeparator(), pair)
+		}
+		key := strings.TrimSpace(kv[0])
+		if !s.config.allowsKey(key) {
+			return fmt.Errorf("unknown key %q for flag %s%s", key, prefixFor(s.name), s.name)
+		}
+		if _, ok := s.m[key]; !ok && s.config.MaxEntries > 0 && len(s.m) >= s.config.MaxEntries {
+			return fmt.Errorf("too many entries, at most %d are allowed", s.config.MaxEntries)
+		}
+		s.m[key] = strings.TrimSpace(kv[1])
+	}
+
+	return nil
+}
+
+// String returns a readable representation of this value (for usage defaults)
+func (s *StringMap) String() string {
+	if s == nil {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(s.m))
+	for _, k := range s.keys() {
+		pairs = append(pairs, k+s.config.keySeparator()+s.m[k])
+	}
+	return strings.Join(pairs, s.config.pairSeparator())
+}
+
+func (s *StringMap) keys() []string {
+	keys := make([]string, 0, len(s.m))
+	for k := range s.m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Value returns the map of strings set by this flag
+func (s *StringMap) Value() map[string]string {
+	return s.m
+}
+
+// Get returns the map of strings set by this flag
+func (s *StringMap) Get() interface{} {
+	return *s
+}
+
+// IsRepeatable allows StringMap to fulfill RepeatableValue
+func (s *StringMap) IsRepeatable() bool {
+	return true
+}
+
+// StringMapFlag is a flag with type *StringMap
+type StringMapFlag struct {
+	Name        string
+	Aliases     []string
+	Usage       string
+	EnvVars     []string
+	FilePath    string
+	Required    bool
+	Hidden      bool
+	Value       *StringMap
+	DefaultText string
+	HasBeenSet  bool
+	Destination *StringMap
+	Config      MapConfig
+
+	FlagOptions
+}
+
+// IsSet returns whether or not the flag has been set through env or file
+func (f *StringMapFlag) IsSet() bool {
+	return f.HasBeenSet
+}
+
+// String returns a readable representation of this value
+// (for usage defaults)
+func (f *StringMapFlag) String() string {
+	return FlagStringer(f)
+}
+
+// Names returns the names of the flag
+func (f *StringMapFlag) Names() []string {
+	return flagNames(f.Name, f.Aliases)
+}
+
+// IsRequired returns whether or not the flag is required
+func (f *StringMapFlag) IsRequired() bool {
+	return f.Required
+}
+
+// TakesValue returns true of the flag takes a value, otherwise false
+func (f *StringMapFlag) TakesValue() bool {
+	return true
+}
+
+// GetUsage returns the usage string for the flag
+func (f *StringMapFlag) GetUsage() string {
+	return f.Usage
+}
+
+// GetValue returns the flags value as string representation and an empty
+// string if the flag takes no value at all.
+func (f *StringMapFlag) GetValue() string {
+	if f.Value != nil {
+		return f.Value.String()
+	}
+	return ""
+}
+
+// IsVisible returns true if the flag is not hidden, otherwise false
+func (f *StringMapFlag) IsVisible() bool {
+	return !f.Hidden
+}
+
+// Apply populates the flag given the flag set and environment
+func (f *StringMapFlag) Apply(set *flag.FlagSet) error {
+	if f.Value == nil {
+		f.Value = &StringMap{}
+	}
+	f.Value.config = f.Config
+	f.Value.name = f.Name
+
+	if f.Destination != nil {
+		f.Destination.m = NewStringMap(f.Value.m).m
+		f.Destination.config = f.Config
+		f.Destination.name = f.Name
+	}
+
+	if val, ok := flagFromEnvOrDeprecatedEnvOrFile(f.EnvVars, f.DeprecatedEnvVars, f.FilePath); ok {
+		destination := f.Value
+		if f.Destination != nil {
+			destination = f.Destination
+		}
+
+		if err := destination.Set(val); err != nil {
+			return fmt.Errorf("could not parse %q as map value for flag %s: %s", val, f.Name, err)
+		}
+
+		// Set this to false so that we reset the map if we then set values from
+		// flags that have already been set by the environment.
+		destination.hasBeenSet = false
+		f.HasBeenSet = true
+	}
+
+	setValue := f.Destination
+	if f.Destination == nil {
+		setValue = f.Value.clone()
+	}
+	for _, name := range f.Names() {
+		set.Var(setValue, name, f.Usage)
+	}
+
+	return nil
+}
+
+// StringMap looks up the value of a local StringMapFlag, returns
+// nil if not found
+func (c *Context) StringMap(name string) map[string]string {
+	if fs := c.lookupFlagSet(name); fs != nil {
+		return lookupStringMap(name, fs)
+	}
+	return nil
+}
+
+func lookupStringMap(name string, set *flag.FlagSet) map[string]string {
+	f := set.Lookup(name)
+	if f != nil {
+		if m, ok := f.Value.(*StringMap); ok {
+			return m.Value()
+		}
+	}
+	return nil
+}
@@ -4,13 +4,26 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"strings"
 )
 
+// StringSliceConfig defines the configuration for string slice flags
+type StringSliceConfig struct {
+	SliceConfig
+	// CombineEnvAndFlag appends the values given on the command line to the
+	// ones from the environment, instead of replacing them
+	CombineEnvAndFlag bool
+	// FromFile takes each value as the name of a file and uses the contents
+	// of the file instead
+	FromFile bool
+}
+
 // StringSlice wraps a []string to satisfy flag.Value
 type StringSlice struct {
 	slice      []string
 	hasBeenSet bool
+	config     StringSliceConfig
 }
 
 // NewStringSlice creates a *StringSlice with default values
@@ -23,6 +36,7 @@ func (s *StringSlice) clone() *StringSlice {
 	n := &StringSlice{
 		slice:      make([]string, len(s.slice)),
 		hasBeenSet: s.hasBeenSet,
+		config:     s.config,
 	}
 	copy(n.slice, s.slice)
 	return n
@@ -42,6 +56,18 @@ func (s *StringSlice) Set(value string) error {
 		return nil
 	}
 
+	if err := s.config.checkItems(len(s.slice)); err != nil {
+		return err
+	}
+
+	if s.config.FromFile {
+		data, err := ioutil.ReadFile(value)
+		if err != nil {
+			return err
+		}
+		value = string(data)
+	}
+
 	s.slice = append(s.slice, value)
 
 	return nil
@@ -68,20 +94,29 @@ func (s *StringSlice) Get() interface{} {
 	return *s
 }
 
+// IsRepeatable allows StringSlice to fulfill RepeatableValue
+func (s *StringSlice) IsRepeatable() bool {
+	return true
+}
+
 // StringSliceFlag is a flag with type *StringSlice
 type StringSliceFlag struct {
-	Name        string
-	Aliases     []string
-	Usage       string
-	EnvVars     []string
-	FilePath    string
-	Required    bool
-	Hidden      bool
-	TakesFile   bool
-	Value       *StringSlice
-	DefaultText string
-	HasBeenSet  bool
-	Destination *StringSlice
+	Name           string
+	Aliases        []string
+	Usage          string
+	EnvVars        []string
+	FilePath       string
+	Required       bool
+	Hidden         bool
+	CompleteValues CompleteValuesFunc
+	TakesFile      bool
+	Value          *StringSlice
+	DefaultText    string
+	HasBeenSet     bool
+	Destination    *StringSlice
+	Config         StringSliceConfig
+
+	FlagOptions
 }
 
 // IsSet returns whether or not the flag has been set through env or file
@@ -129,6 +164,11 @@ func (f *StringSliceFlag) IsVisible() bool {
 	return !f.Hidden
 }
 
+// completeValuesFunc returns the CompleteValues function of the flag
+func (f *StringSliceFlag) completeValuesFunc() CompleteValuesFunc {
+	return f.CompleteValues
+}
+
 // Apply populates the flag given the flag set and environment
 func (f *StringSliceFlag) Apply(set *flag.FlagSet) error {
 
@@ -138,7 +178,8 @@ func (f *StringSliceFlag) Apply(set *flag.FlagSet) error {
 
 	}
 
-	if val, ok := flagFromEnvOrFile(f.EnvVars, f.FilePath); ok {
+	fromEnv := false
+	if val, ok := flagFromEnvOrDeprecatedEnvOrFile(f.EnvVars, f.DeprecatedEnvVars, f.FilePath); ok {
 		if f.Value == nil {
 			f.Value = &StringSlice{}
 		}
@@ -146,6 +187,8 @@ func (f *StringSliceFlag) Apply(set *flag.FlagSet) error {
 		if f.Destination != nil {
 			destination = f.Destination
 		}
+		destination.config = f.Config
+		destination.hasBeenSet = false
 
 		for _, s := range strings.Split(val, ",") {
 			if err := destination.Set(strings.TrimSpace(s)); err != nil {
@@ -157,6 +200,7 @@ func (f *StringSliceFlag) Apply(set *flag.FlagSet) error {
 		// flags that have already been set by the environment.
 		destination.hasBeenSet = false
 		f.HasBeenSet = true
+		fromEnv = true
 	}
 
 	if f.Value == nil {
@@ -166,6 +210,12 @@ func (f *StringSliceFlag) Apply(set *flag.FlagSet) error {
 	if f.Destination == nil {
 		setValue = f.Value.clone()
 	}
+	setValue.config = f.Config
+	// Values from the command line are appended to the ones from the
+	// environment if they are to be combined. This is only set on the value
+	// of this Apply, so that applying the flag again does not keep it.
+	setValue.hasBeenSet = fromEnv && f.Config.CombineEnvAndFlag
+
 	for _, name := range f.Names() {
 		set.Var(setValue, name, f.Usage)
 	}
@@ -1,11 +1,14 @@
 package cli
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
 	"runtime"
@@ -40,6 +43,11 @@ func TestBoolFlagHelpOutput(t *testing.T) {
 	}
 }
 
+func TestBoolFlagHelpOutputShowValue(t *testing.T) {
+	fl := &BoolFlag{Name: "color", Aliases: []string{"c"}, Usage: "colorize output", Config: BoolConfig{ShowValueInHelp: true}}
+	expect(t, fl.String(), "--color[=true|false], -c[=true|false]\tcolorize output (default: false)")
+}
+
 func TestBoolFlagApply_SetsAllNames(t *testing.T) {
 	v := false
 	fl := BoolFlag{Name: "wat", Aliases: []string{"W", "huh"}, Destination: &v}
@@ -358,6 +366,19 @@ func TestStringSliceFlagHelpOutput(t *testing.T) {
 	}
 }
 
+func TestSliceFlagHelpOutput_DefaultTextMaxItems(t *testing.T) {
+	config := SliceConfig{DefaultTextMaxItems: 2}
+
+	sf := &StringSliceFlag{Name: "zone", Value: NewStringSlice("a", "b", "c", "d", "e", "f", "g"), Config: StringSliceConfig{SliceConfig: config}}
+	expect(t, sf.String(), "--zone value\t(default: \"a\", \"b\", ... (+5 more))\t(accepts multiple inputs)")
+
+	isf := &IntSliceFlag{Name: "port", Value: NewIntSlice(80, 443, 8080), Config: config}
+	expect(t, isf.String(), "--port value\t(default: 80, 443, ... (+1 more))\t(accepts multiple inputs)")
+
+	short := &IntSliceFlag{Name: "port", Value: NewIntSlice(80, 443), Config: config}
+	expect(t, short.String(), "--port value\t(default: 80, 443)\t(accepts multiple inputs)")
+}
+
 func TestStringSliceFlagWithEnvVarHelpOutput(t *testing.T) {
 	defer resetEnv(os.Environ())
 	os.Clearenv()
@@ -624,6 +645,98 @@ func TestDurationFlagApply_SetsAllNames(t *testing.T) {
 	expect(t, v, time.Hour*30)
 }
 
+func TestDurationFlagKeywords(t *testing.T) {
+	defer resetEnv(os.Environ())
+	config := DurationConfig{Keywords: map[string]time.Duration{
+		"none": 0,
+		"max":  time.Duration(1 << 62),
+	}}
+
+	cases := []struct {
+		args     []string
+		env      string
+		expected time.Duration
+		err      string
+	}{
+		{args: []string{"run"}, expected: time.Minute},
+		{args: []string{"run", "--timeout", "none"}, expected: 0},
+		{args: []string{"run", "-t", "max"}, expected: time.Duration(1 << 62)},
+		{args: []string{"run", "--timeout", "90s"}, expected: 90 * time.Second},
+		{args: []string{"run"}, env: "none", expected: 0},
+		{args: []string{"run"}, env: "2h", expected: 2 * time.Hour},
+		{args: []string{"run", "--timeout", "forever"}, err: `invalid value "forever" for flag -timeout: .*`},
+	}
+
+	for _, c := range cases {
+		os.Clearenv()
+		if c.env != "" {
+			_ = os.Setenv("APP_TIMEOUT", c.env)
+		}
+
+		var timeout time.Duration
+		err := (&App{
+			Flags: []Flag{
+				&DurationFlag{Name: "timeout", Aliases: []string{"t"}, Value: time.Minute, EnvVars: []string{"APP_TIMEOUT"}, Config: config},
+			},
+			Action: func(ctx *Context) error {
+				timeout = ctx.Duration("t")
+				return nil
+			},
+			Writer: ioutil.Discard,
+		}).Run(c.args)
+
+		if c.err != "" {
+			if err == nil || !regexp.MustCompile(c.err).MatchString(err.Error()) {
+				t.Errorf("expected error matching %q, got %v", c.err, err)
+			}
+			continue
+		}
+		expect(t, err, nil)
+		expect(t, timeout, c.expected)
+	}
+}
+
+func TestFlagOnParseError(t *testing.T) {
+	onParseError := func(raw string, err error) (string, bool) {
+		if raw == "fail" {
+			return "port must be a number", false
+		}
+		return "8080", true
+	}
+
+	cases := []struct {
+		args     []string
+		expected int
+		err      string
+	}{
+		{args: []string{"run", "--port", "9090"}, expected: 9090},
+		{args: []string{"run", "--port", "http"}, expected: 8080},
+		{args: []string{"run", "-p", "http"}, expected: 8080},
+		{args: []string{"run", "--port", "fail"}, err: `invalid value "fail" for flag -port: port must be a number`},
+	}
+
+	for _, c := range cases {
+		var port int
+		err := (&App{
+			Flags: []Flag{
+				&IntFlag{Name: "port", Aliases: []string{"p"}, FlagOptions: FlagOptions{OnParseError: onParseError}},
+			},
+			Action: func(ctx *Context) error {
+				port = ctx.Int("port")
+				return nil
+			},
+			Writer: ioutil.Discard,
+		}).Run(c.args)
+
+		if c.err != "" {
+			expect(t, err, errors.New(c.err))
+			continue
+		}
+		expect(t, err, nil)
+		expect(t, port, c.expected)
+	}
+}
+
 var intSliceFlagTests = []struct {
 	name     string
 	aliases  []string
@@ -1022,6 +1135,49 @@ func TestParseMultiStringFromEnvCascade(t *testing.T) {
 	}).Run([]string{"run"})
 }
 
+func TestParseStringFromDeprecatedEnv(t *testing.T) {
+	defer resetEnv(os.Environ())
+
+	cases := []struct {
+		env     map[string]string
+		args    []string
+		value   string
+		warning string
+	}{
+		{env: map[string]string{"APP_NAME": "new"}, value: "new", warning: ""},
+		{env: map[string]string{"APP_NAME": "new", "OLD_NAME": "old"}, value: "new", warning: ""},
+		{env: map[string]string{"OLD_NAME": "old"}, value: "old", warning: "warning: environment variable \"OLD_NAME\" is deprecated, use \"APP_NAME\" instead\n"},
+		{env: map[string]string{"OLD_NAME": "old"}, args: []string{"-ab"}, value: "old", warning: "warning: environment variable \"OLD_NAME\" is deprecated, use \"APP_NAME\" instead\n"},
+	}
+
+	for _, c := range cases {
+		os.Clearenv()
+		for k, v := range c.env {
+			_ = os.Setenv(k, v)
+		}
+		errWriter := &bytes.Buffer{}
+
+		var value string
+		err := (&App{
+			ErrWriter:              errWriter,
+			UseShortOptionHandling: true,
+			Flags: []Flag{
+				&StringFlag{Name: "name", EnvVars: []string{"APP_NAME"}, FlagOptions: FlagOptions{DeprecatedEnvVars: []string{"OLD_NAME"}}},
+				&BoolFlag{Name: "a"},
+				&BoolFlag{Name: "b"},
+			},
+			Action: func(ctx *Context) error {
+				value = ctx.String("name")
+				return nil
+			},
+		}).Run(append([]string{"run"}, c.args...))
+
+		expect(t, err, nil)
+		expect(t, value, c.value)
+		expect(t, errWriter.String(), c.warning)
+	}
+}
+
 func TestParseMultiStringSlice(t *testing.T) {
 	_ = (&App{
 		Flags: []Flag{
@@ -1138,6 +1294,400 @@ func TestParseMultiStringSliceFromEnv(t *testing.T) {
 	}).Run([]string{"run"})
 }
 
+func TestEnvOnlyFlag(t *testing.T) {
+	defer resetEnv(os.Environ())
+	os.Clearenv()
+	_ = os.Setenv("APP_TOKEN", "s3cr3t")
+
+	var token string
+	app := &App{
+		Writer: ioutil.Discard,
+		Flags: []Flag{
+			&StringFlag{Name: "token", Aliases: []string{"t"}, EnvVars: []string{"APP_TOKEN"}, FlagOptions: FlagOptions{EnvOnly: true}},
+		},
+		Action: func(ctx *Context) error {
+			token = ctx.String("token")
+			return nil
+		},
+	}
+
+	expect(t, app.Flags[0].String(), "$APP_TOKEN\t(env only)")
+
+	err := app.Run([]string{"run"})
+	expect(t, err, nil)
+	expect(t, token, "s3cr3t")
+
+	err = app.Run([]string{"run", "-t", "leaked"})
+	expect(t, err, errors.New("flag provided but not defined: -t"))
+
+	err = app.Run([]string{"run", "--token", "leaked"})
+	expect(t, err, errors.New("flag provided but not defined: -token"))
+
+	expect(t, len(app.VisibleFlags()), 2)
+	expect(t, app.VisibleFlags()[0], app.Flags[0])
+
+	out := &bytes.Buffer{}
+	app.Writer = out
+	err = app.Run([]string{"run", "--help"})
+	expect(t, err, nil)
+	if !strings.Contains(out.String(), "$APP_TOKEN  (env only)") {
+		t.Errorf("expected env only flag in help, got %q", out.String())
+	}
+	if strings.Contains(out.String(), "--token") {
+		t.Errorf("expected no command line form of env only flag in help, got %q", out.String())
+	}
+}
+
+type fakeTerminal struct {
+	bytes.Buffer
+	terminal bool
+}
+
+func (f *fakeTerminal) IsTerminal() bool {
+	return f.terminal
+}
+
+func TestRequireTTYFlag(t *testing.T) {
+	term := &fakeTerminal{}
+	app := &App{
+		Reader: term,
+		Writer: term,
+		Commands: []*Command{
+			{
+				Name:   "edit",
+				Flags:  []Flag{&BoolFlag{Name: "interactive", Aliases: []string{"i"}, FlagOptions: FlagOptions{RequireTTY: true}}},
+				Action: func(ctx *Context) error { return nil },
+			},
+		},
+	}
+
+	err := app.Run([]string{"run", "edit", "--interactive"})
+	expect(t, err, errors.New("--interactive requires a terminal"))
+
+	err = app.Run([]string{"run", "edit"})
+	expect(t, err, nil)
+
+	term.terminal = true
+	err = app.Run([]string{"run", "edit", "-i"})
+	expect(t, err, nil)
+
+	app.Reader = &bytes.Buffer{}
+	err = app.Run([]string{"run", "edit", "-i"})
+	expect(t, err, errors.New("-i requires a terminal"))
+}
+
+func TestEnvOverridesFlag(t *testing.T) {
+	defer resetEnv(os.Environ())
+	os.Clearenv()
+
+	run := func(overrides bool, args ...string) string {
+		var level string
+		err := (&App{
+			Flags: []Flag{
+				&StringFlag{Name: "level", EnvVars: []string{"APP_LEVEL"}, FlagOptions: FlagOptions{EnvOverridesFlag: overrides}},
+			},
+			Action: func(ctx *Context) error {
+				level = ctx.String("level")
+				return nil
+			},
+		}).Run(append([]string{"run"}, args...))
+		expect(t, err, nil)
+		return level
+	}
+
+	expect(t, run(true, "--level", "info"), "info")
+
+	_ = os.Setenv("APP_LEVEL", "debug")
+	expect(t, run(false, "--level", "info"), "info")
+	expect(t, run(true, "--level", "info"), "debug")
+	expect(t, run(true), "debug")
+}
+
+func TestEnvOverridesFlag_ReusedApp(t *testing.T) {
+	defer resetEnv(os.Environ())
+	os.Clearenv()
+
+	var level string
+	app := &App{
+		Flags: []Flag{
+			&StringFlag{Name: "level", EnvVars: []string{"APP_LEVEL"}, FlagOptions: FlagOptions{EnvOverridesFlag: true}},
+		},
+		Action: func(ctx *Context) error {
+			level = ctx.String("level")
+			return nil
+		},
+	}
+
+	_ = os.Setenv("APP_LEVEL", "debug")
+	expect(t, app.Run([]string{"run", "--level", "info"}), nil)
+	expect(t, level, "debug")
+
+	_ = os.Unsetenv("APP_LEVEL")
+	expect(t, app.Run([]string{"run", "--level", "info"}), nil)
+	expect(t, level, "info")
+
+	_ = os.Setenv("APP_LEVEL", "")
+	expect(t, app.Run([]string{"run", "--level", "info"}), nil)
+	expect(t, level, "info")
+}
+
+func TestParseStringMap(t *testing.T) {
+	var actual map[string]string
+	app := &App{
+		Writer: ioutil.Discard,
+		Flags: []Flag{
+			&StringMapFlag{Name: "env", Aliases: []string{"e"}, Value: NewStringMap(map[string]string{"a": "0"})},
+			&StringMapFlag{Name: "label", Config: MapConfig{PairSeparator: ";", KeySeparator: ":"}},
+		},
+		Action: func(ctx *Context) error {
+			actual = ctx.StringMap("env")
+			expect(t, ctx.StringMap("e"), actual)
+			expect(t, ctx.StringMap("label"), map[string]string{"tier": "web", "zone": "a,b"})
+			return nil
+		},
+	}
+
+	err := app.Run([]string{"run", "--env", "a=1;b=2", "--label", "tier:web;zone:a,b"})
+	expect(t, err, nil)
+	expect(t, actual, map[string]string{"a": "1;b=2"})
+
+	app.Flags[0].(*StringMapFlag).Config = MapConfig{PairSeparator: ";"}
+	err = app.Run([]string{"run", "--env", "a=1;b=2", "--env", "c=3", "--label", "tier:web;zone:a,b"})
+	expect(t, err, nil)
+	expect(t, actual, map[string]string{"a": "1", "b": "2", "c": "3"})
+
+	err = app.Run([]string{"run", "--env", "a"})
+	if err == nil {
+		t.Error("expected an error for an entry without key separator")
+	}
+}
+
+func TestParseStringMapFromEnv(t *testing.T) {
+	defer resetEnv(os.Environ())
+	os.Clearenv()
+	_ = os.Setenv("APP_ENV", "a=>1 ; b=>2")
+
+	var actual map[string]string
+	err := (&App{
+		Flags: []Flag{
+			&StringMapFlag{Name: "env", EnvVars: []string{"APP_ENV"}, Config: MapConfig{PairSeparator: ";", KeySeparator: "=>"}},
+		},
+		Action: func(ctx *Context) error {
+			actual = ctx.StringMap("env")
+			return nil
+		},
+	}).Run([]string{"run"})
+	expect(t, err, nil)
+	expect(t, actual, map[string]string{"a": "1", "b": "2"})
+}
+
+func TestStringMapAllowedKeys(t *testing.T) {
+	defer resetEnv(os.Environ())
+	os.Clearenv()
+
+	newApp := func() *App {
+		return &App{
+			Writer: ioutil.Discard,
+			Flags: []Flag{
+				&StringMapFlag{Name: "labels", EnvVars: []string{"APP_LABELS"}, Config: MapConfig{AllowedKeys: []string{"tier", "zone"}}},
+			},
+			Action: func(ctx *Context) error { return nil },
+		}
+	}
+
+	err := newApp().Run([]string{"run", "--labels", "tier=web,zone=a"})
+	expect(t, err, nil)
+
+	err = newApp().Run([]string{"run", "--labels", "tier=web,x=1"})
+	if err == nil || !strings.Contains(err.Error(), `unknown key "x" for flag --labels`) {
+		t.Errorf("expected unknown key error from the command line, got %v", err)
+	}
+
+	_ = os.Setenv("APP_LABELS", "zone=b")
+	err = newApp().Run([]string{"run"})
+	expect(t, err, nil)
+
+	_ = os.Setenv("APP_LABELS", "zone=b,x=1")
+	err = newApp().Run([]string{"run"})
+	if err == nil || !strings.Contains(err.Error(), `unknown key "x" for flag --labels`) {
+		t.Errorf("expected unknown key error from the environment, got %v", err)
+	}
+}
+
+func TestSliceAndMapLimits(t *testing.T) {
+	defer resetEnv(os.Environ())
+	os.Clearenv()
+
+	newApp := func() *App {
+		return &App{
+			Writer: ioutil.Discard,
+			Flags: []Flag{
+				&StringSliceFlag{Name: "tag", EnvVars: []string{"APP_TAGS"}, Config: StringSliceConfig{SliceConfig: SliceConfig{MaxItems: 2}}},
+				&IntSliceFlag{Name: "port", EnvVars: []string{"APP_PORTS"}, Config: SliceConfig{MaxItems: 2}},
+				&StringMapFlag{Name: "label", EnvVars: []string{"APP_LABELS"}, Config: MapConfig{MaxEntries: 2}},
+			},
+			Action: func(ctx *Context) error { return nil },
+		}
+	}
+
+	err := newApp().Run([]string{"run", "--tag", "a", "--tag", "b", "--port", "1", "--label", "a=1,b=2,a=3"})
+	expect(t, err, nil)
+
+	for _, args := range [][]string{
+		{"--tag", "a", "--tag", "b", "--tag", "c"},
+		{"--port", "1", "--port", "2", "--port", "3"},
+		{"--label", "a=1,b=2", "--label", "c=3"},
+	} {
+		err = newApp().Run(append([]string{"run"}, args...))
+		if err == nil || !strings.Contains(err.Error(), "at most 2 are allowed") {
+			t.Errorf("expected the limit to be enforced for %v, got %v", args, err)
+		}
+	}
+
+	for _, env := range []string{"APP_TAGS=a,b,c", "APP_PORTS=1,2,3", "APP_LABELS=a=1,b=2,c=3"} {
+		os.Clearenv()
+		kv := strings.SplitN(env, "=", 2)
+		_ = os.Setenv(kv[0], kv[1])
+
+		err = newApp().Run([]string{"run"})
+		if err == nil || !strings.Contains(err.Error(), "at most 2 are allowed") {
+			t.Errorf("expected the limit to be enforced for %s, got %v", env, err)
+		}
+	}
+}
+
+func TestStringSliceCombineEnvAndFlag(t *testing.T) {
+	defer resetEnv(os.Environ())
+	os.Clearenv()
+	_ = os.Setenv("APP_INCLUDE", "a,b")
+
+	run := func(combine bool, args ...string) []string {
+		var include []string
+		err := (&App{
+			Flags: []Flag{
+				&StringSliceFlag{Name: "include", EnvVars: []string{"APP_INCLUDE"}, Config: StringSliceConfig{CombineEnvAndFlag: combine}},
+			},
+			Action: func(ctx *Context) error {
+				include = ctx.StringSlice("include")
+				return nil
+			},
+		}).Run(append([]string{"run"}, args...))
+		expect(t, err, nil)
+		return include
+	}
+
+	expect(t, run(false, "--include", "c"), []string{"c"})
+	expect(t, run(true, "--include", "c", "--include", "d"), []string{"a", "b", "c", "d"})
+	expect(t, run(true), []string{"a", "b"})
+}
+
+func TestStringSliceCombineEnvAndFlag_Reapplied(t *testing.T) {
+	defer resetEnv(os.Environ())
+	os.Clearenv()
+	_ = os.Setenv("APP_INCLUDE", "a,b")
+
+	var include []string
+	app := &App{
+		UseShortOptionHandling: true,
+		Flags: []Flag{
+			&StringSliceFlag{Name: "include", EnvVars: []string{"APP_INCLUDE"}, Config: StringSliceConfig{CombineEnvAndFlag: true}},
+			&BoolFlag{Name: "p"},
+			&BoolFlag{Name: "q"},
+		},
+		Action: func(ctx *Context) error {
+			include = ctx.StringSlice("include")
+			return nil
+		},
+	}
+
+	// running the same app again must not add the env values twice
+	for i := 0; i < 2; i++ {
+		err := app.Run([]string{"run", "--include", "c"})
+		expect(t, err, nil)
+		expect(t, include, []string{"a", "b", "c"})
+	}
+
+	// neither must the flag set being rebuilt to split short options
+	err := app.Run([]string{"run", "-pq", "--include", "c"})
+	expect(t, err, nil)
+	expect(t, include, []string{"a", "b", "c"})
+}
+
+func TestStringFlag_RejectSeparator(t *testing.T) {
+	defer resetEnv(os.Environ())
+	os.Clearenv()
+
+	var name string
+	run := func(config StringConfig, args ...string) error {
+		app := &App{
+			Writer: ioutil.Discard,
+			Flags: []Flag{
+				&StringFlag{Name: "name", Aliases: []string{"n"}, EnvVars: []string{"APP_NAME"}, Config: config},
+			},
+			Action: func(ctx *Context) error {
+				name = ctx.String("name")
+				return nil
+			},
+		}
+		return app.Run(append([]string{"app"}, args...))
+	}
+
+	err := run(StringConfig{}, "--name", "a,b")
+	expect(t, err, nil)
+	expect(t, name, "a,b")
+
+	err = run(StringConfig{RejectSeparator: true}, "--name", "a")
+	expect(t, err, nil)
+	expect(t, name, "a")
+
+	err = run(StringConfig{RejectSeparator: true}, "-n", "a,b")
+	expect(t, err, errors.New(`invalid value "a,b" for flag -n: must not contain ",", --name takes a single value`))
+
+	_ = os.Setenv("APP_NAME", "a,b")
+	err = run(StringConfig{RejectSeparator: true})
+	expect(t, err, errors.New(`could not parse "a,b" as string value for flag name: must not contain ",", --name takes a single value`))
+}
+
+func TestStringSliceFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "urfave_cli_certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cert1 := filepath.Join(dir, "cert1.pem")
+	cert2 := filepath.Join(dir, "cert2.pem")
+	_ = ioutil.WriteFile(cert1, []byte("first"), 0644)
+	_ = ioutil.WriteFile(cert2, []byte("second"), 0644)
+
+	var certs []string
+	app := &App{
+		Writer: ioutil.Discard,
+		Flags: []Flag{
+			&StringSliceFlag{Name: "cert", Config: StringSliceConfig{FromFile: true}},
+		},
+		Action: func(ctx *Context) error {
+			certs = ctx.StringSlice("cert")
+			return nil
+		},
+	}
+
+	err = app.Run([]string{"run", "--cert", cert1, "--cert", cert2})
+	expect(t, err, nil)
+	expect(t, certs, []string{"first", "second"})
+
+	missing := filepath.Join(dir, "missing.pem")
+	err = app.Run([]string{"run", "--cert", cert1, "--cert", missing})
+	if err == nil || !strings.Contains(err.Error(), missing) {
+		t.Errorf("expected an error naming the missing file, got %v", err)
+	}
+}
+
+func TestStringMapFlagHelpOutput(t *testing.T) {
+	fl := &StringMapFlag{Name: "env", Usage: "set `VARS`", Value: NewStringMap(map[string]string{"b": "2", "a": "1"})}
+	expect(t, fl.String(), "--env VARS\tset VARS (default: \"a=1\", \"b=2\")\t(accepts multiple inputs)")
+}
+
 func TestParseMultiStringSliceFromEnvWithDefaults(t *testing.T) {
 	defer resetEnv(os.Environ())
 	os.Clearenv()
@@ -2050,3 +2600,32 @@ func TestTimestampFlagApply_WithDestination(t *testing.T) {
 	expect(t, err, nil)
 	expect(t, *fl.Destination.timestamp, expectedResult)
 }
+
+func TestTimestampFlag_DefaultTimezone(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	own := time.FixedZone("UTC-5", -5*60*60)
+	var start, end *time.Time
+	app := &App{
+		Commands: []*Command{
+			{
+				Name:            "schedule",
+				DefaultTimezone: loc,
+				Flags: []Flag{
+					&TimestampFlag{Name: "start", Layout: "2006-01-02 15:04"},
+					&TimestampFlag{Name: "end", Layout: "2006-01-02 15:04", Timezone: own},
+				},
+				Action: func(ctx *Context) error {
+					start = ctx.Timestamp("start")
+					end = ctx.Timestamp("end")
+					return nil
+				},
+			},
+		},
+	}
+
+	err := app.Run([]string{"app", "schedule", "--start", "2021-03-01 09:00", "--end", "2021-03-01 17:00"})
+	expect(t, err, nil)
+	expect(t, start.Location(), loc)
+	expect(t, start.Equal(time.Date(2021, 3, 1, 9, 0, 0, 0, loc)), true)
+	expect(t, end.Location(), own)
+}
@@ -11,6 +11,7 @@ type Timestamp struct {
 	timestamp  *time.Time
 	hasBeenSet bool
 	layout     string
+	location   *time.Location
 }
 
 // Timestamp constructor
@@ -31,9 +32,20 @@ func (t *Timestamp) SetLayout(layout string) {
 	t.layout = layout
 }
 
+// Set the location in which timestamps without a time zone are parsed
+func (t *Timestamp) SetLocation(loc *time.Location) {
+	t.location = loc
+}
+
 // Parses the string value to timestamp
 func (t *Timestamp) Set(value string) error {
-	timestamp, err := time.Parse(t.layout, value)
+	var timestamp time.Time
+	var err error
+	if t.location != nil {
+		timestamp, err = time.ParseInLocation(t.layout, value, t.location)
+	} else {
+		timestamp, err = time.Parse(t.layout, value)
+	}
 	if err != nil {
 		return err
 	}
@@ -68,10 +80,13 @@ type TimestampFlag struct {
 	Required    bool
 	Hidden      bool
 	Layout      string
+	Timezone    *time.Location
 	Value       *Timestamp
 	DefaultText string
 	HasBeenSet  bool
 	Destination *Timestamp
+
+	FlagOptions
 }
 
 // IsSet returns whether or not the flag has been set through env or file
@@ -121,6 +136,12 @@ func (f *TimestampFlag) IsVisible() bool {
 
 // Apply populates the flag given the flag set and environment
 func (f *TimestampFlag) Apply(set *flag.FlagSet) error {
+	return f.applyInTimezone(set, nil)
+}
+
+// applyInTimezone is Apply for a command whose DefaultTimezone is loc, which
+// is used if the flag does not set its own Timezone
+func (f *TimestampFlag) applyInTimezone(set *flag.FlagSet, loc *time.Location) error {
 	if f.Layout == "" {
 		return fmt.Errorf("timestamp Layout is required")
 	}
@@ -129,11 +150,17 @@ func (f *TimestampFlag) Apply(set *flag.FlagSet) error {
 	}
 	f.Value.SetLayout(f.Layout)
 
+	if f.Timezone != nil {
+		loc = f.Timezone
+	}
+	f.Value.SetLocation(loc)
+
 	if f.Destination != nil {
 		f.Destination.SetLayout(f.Layout)
+		f.Destination.SetLocation(loc)
 	}
 
-	if val, ok := flagFromEnvOrFile(f.EnvVars, f.FilePath); ok {
+	if val, ok := flagFromEnvOrDeprecatedEnvOrFile(f.EnvVars, f.DeprecatedEnvVars, f.FilePath); ok {
 		if err := f.Value.Set(val); err != nil {
 			return fmt.Errorf("could not parse %q as timestamp value for flag %s: %s", val, f.Name, err)
 		}
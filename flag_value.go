@@ -0,0 +1,30 @@
+//go:build go1.18
+// +build go1.18
+
+package cli
+
+import "flag"
+
+// FlagValue looks up the value of the flag with the given name and returns
+// it as a T. It returns false if there is no such flag or if its value is not
+// a T. Values which implement flag.Getter are returned as given by Get, others,
+// e.g. the Generic of a GenericFlag, as they are.
+func FlagValue[T any](ctx *Context, name string) (T, bool) {
+	var zero T
+
+	fs := ctx.lookupFlagSet(name)
+	if fs == nil {
+		return zero, false
+	}
+
+	var value interface{} = fs.Lookup(name).Value
+	if getter, ok := value.(flag.Getter); ok {
+		value = getter.Get()
+	}
+
+	v, ok := value.(T)
+	if !ok {
+		return zero, false
+	}
+	return v, true
+}
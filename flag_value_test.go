@@ -0,0 +1,36 @@
+//go:build go1.18
+// +build go1.18
+
+package cli
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestFlagValue_String(t *testing.T) {
+	set := flag.NewFlagSet("test", 0)
+	set.String("name", "bob", "")
+	ctx := NewContext(nil, set, nil)
+
+	v, ok := FlagValue[string](ctx, "name")
+	expect(t, ok, true)
+	expect(t, v, "bob")
+
+	_, ok = FlagValue[int](ctx, "name")
+	expect(t, ok, false)
+
+	_, ok = FlagValue[string](ctx, "missing")
+	expect(t, ok, false)
+}
+
+func TestFlagValue_Generic(t *testing.T) {
+	set := flag.NewFlagSet("test", 0)
+	_ = (&GenericFlag{Name: "serve", Value: &Parser{}}).Apply(set)
+	_ = set.Parse([]string{"--serve", "10,20"})
+	ctx := NewContext(nil, set, nil)
+
+	v, ok := FlagValue[*Parser](ctx, "serve")
+	expect(t, ok, true)
+	expect(t, v.String(), "10,20")
+}
@@ -23,6 +23,17 @@ type CommandNotFoundFunc func(*Context, string)
 // is displayed and the execution is interrupted.
 type OnUsageErrorFunc func(context *Context, err error, isSubcommand bool) error
 
+// ShortFlagResolverFunc is used with short-option handling to split a combined
+// short option, given without its leading dash, into the names of the flags it
+// stands for. Returning false falls back to splitting it by the longest
+// defined flag names.
+type ShortFlagResolverFunc func(remaining string) ([]string, bool)
+
+// ParseErrorFunc is executed when a value given for a flag on the command line
+// fails to parse. Returning true recovers by setting the returned value
+// instead. Otherwise, a non-empty returned value replaces the error message.
+type ParseErrorFunc func(raw string, err error) (value string, handled bool)
+
 // ExitErrHandlerFunc is executed if provided in order to handle exitError values
 // returned by Actions and Before/After functions.
 type ExitErrHandlerFunc func(context *Context, err error)
@@ -42,3 +53,19 @@ type FlagEnvHintFunc func(envVars []string, str string) string
 // FlagFileHintFunc is used by the default FlagStringFunc to annotate flag help
 // with the file path details.
 type FlagFileHintFunc func(filePath, str string) string
+
+// CompleteValuesFunc returns a channel of the values to offer when completing
+// the value of a flag, so that they are written as they are produced. The
+// channel must be closed once all values were sent. Sending should stop when
+// the Context is done, which it is once completion does not need more values.
+type CompleteValuesFunc func(*Context) <-chan string
+
+// NormalizeFlagsFunc is executed after the flags have been parsed, but before
+// the required flags are checked, to canonicalize flag values with
+// Context.Set. If a non-nil error is returned, nothing else is run.
+type NormalizeFlagsFunc func(*Context) error
+
+// OnResolvedFunc is executed when all flags have been parsed and checked,
+// right before the Action of the command which is run. If a non-nil error is
+// returned, the Action is not run.
+type OnResolvedFunc func(*Context) error
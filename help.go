@@ -131,6 +131,15 @@ func cliArgContains(flagName string) bool {
 	return false
 }
 
+func printChoiceSuggestions(choices *ChoiceArgs, writer io.Writer) {
+	if choices == nil {
+		return
+	}
+	for _, value := range choices.AllowedValues {
+		_, _ = fmt.Fprintln(writer, value)
+	}
+}
+
 func printFlagSuggestions(lastArg string, flags []Flag, writer io.Writer) {
 	cur := strings.TrimPrefix(lastArg, "-")
 	cur = strings.TrimPrefix(cur, "-")
@@ -138,6 +147,9 @@ func printFlagSuggestions(lastArg string, flags []Flag, writer io.Writer) {
 		if bflag, ok := flag.(*BoolFlag); ok && bflag.Hidden {
 			continue
 		}
+		if flagOptions(flag).EnvOnly {
+			continue
+		}
 		for _, name := range flag.Names() {
 			name = strings.TrimSpace(name)
 			// this will get total count utf8 letters in flag name
@@ -159,10 +171,56 @@ func printFlagSuggestions(lastArg string, flags []Flag, writer io.Writer) {
 	}
 }
 
+// completionValueFlag returns the flag which takes the value being completed
+// after lastArg, or nil if lastArg is not such a flag
+func completionValueFlag(lastArg string, flags []Flag, cmd *Command) Flag {
+	if !strings.HasPrefix(lastArg, "-") || strings.Contains(lastArg, "=") {
+		return nil
+	}
+	if cmd != nil {
+		flags = append(append([]Flag{}, flags...), cmd.Flags...)
+	}
+	if f := lookupFlagArg(flags, lastArg); f != nil && flagTakesValue(f) {
+		return f
+	}
+	return nil
+}
+
+// completionAssignedFlag returns the flag whose value is being completed in
+// the "--flag=value" form of lastArg, along with the "--flag" part and the
+// partial value, or nil if lastArg is not such a flag
+func completionAssignedFlag(lastArg string, flags []Flag, cmd *Command) (Flag, string, string) {
+	i := strings.Index(lastArg, "=")
+	if !strings.HasPrefix(lastArg, "-") || i < 0 {
+		return nil, "", ""
+	}
+	if f := completionValueFlag(lastArg[:i], flags, cmd); f != nil {
+		return f, lastArg[:i], lastArg[i+1:]
+	}
+	return nil, "", ""
+}
+
 func DefaultCompleteWithFlags(cmd *Command) func(c *Context) {
 	return func(c *Context) {
 		if len(os.Args) > 2 {
 			lastArg := os.Args[len(os.Args)-2]
+			if f, name, cur := completionAssignedFlag(lastArg, c.App.Flags, cmd); f != nil {
+				completeValues(c, f, func(value string) bool {
+					if !strings.HasPrefix(value, cur) {
+						return true
+					}
+					_, err := fmt.Fprintf(c.App.Writer, "%s=%s\n", name, value)
+					return err == nil
+				})
+				return
+			}
+			if f := completionValueFlag(lastArg, c.App.Flags, cmd); f != nil {
+				completeValues(c, f, func(value string) bool {
+					_, err := fmt.Fprintln(c.App.Writer, value)
+					return err == nil
+				})
+				return
+			}
 			if strings.HasPrefix(lastArg, "-") {
 				printFlagSuggestions(lastArg, c.App.Flags, c.App.Writer)
 				if cmd != nil {
@@ -173,8 +231,12 @@ func DefaultCompleteWithFlags(cmd *Command) func(c *Context) {
 		}
 		if cmd != nil {
 			printCommandSuggestions(cmd.Subcommands, c.App.Writer)
+			printChoiceSuggestions(cmd.ChoiceArgs, c.App.Writer)
 		} else {
+			printChoiceSuggestions(c.App.ChoiceArgs, c.App.Writer)
 			printCommandSuggestions(c.App.Commands, c.App.Writer)
+			printCommandSuggestions(c.App.lazyCommandListing(), c.App.Writer)
+			printCommandSuggestions(c.App.discoverCommands(c), c.App.Writer)
 		}
 	}
 }
@@ -193,17 +255,15 @@ func ShowCommandHelp(ctx *Context, command string) error {
 		return nil
 	}
 
-	for _, c := range ctx.App.Commands {
-		if c.HasName(command) {
-			templ := c.CustomHelpTemplate
-			if templ == "" {
-				templ = CommandHelpTemplate
-			}
+	if c := ctx.App.lookupCommand(ctx, command); c != nil {
+		templ := c.CustomHelpTemplate
+		if templ == "" {
+			templ = CommandHelpTemplate
+		}
 
-			HelpPrinter(ctx.App.Writer, templ, c)
+		HelpPrinter(ctx.App.Writer, templ, c)
 
-			return nil
-		}
+		return nil
 	}
 
 	if ctx.App.CommandNotFound == nil {
@@ -245,6 +305,10 @@ func printVersion(c *Context) {
 // ShowCompletions prints the lists of commands within a given context
 func ShowCompletions(c *Context) {
 	a := c.App
+	if c.completeFunc != nil {
+		c.completeFunc(c, nil)
+		return
+	}
 	if a != nil && a.BashComplete != nil {
 		a.BashComplete(c)
 	}
@@ -252,9 +316,11 @@ func ShowCompletions(c *Context) {
 
 // ShowCommandCompletions prints the custom completions for a given command
 func ShowCommandCompletions(ctx *Context, command string) {
-	c := ctx.App.Command(command)
+	c := ctx.App.lookupCommand(ctx, command)
 	if c != nil {
-		if c.BashComplete != nil {
+		if ctx.completeFunc != nil {
+			ctx.completeFunc(ctx, c)
+		} else if c.BashComplete != nil {
 			c.BashComplete(ctx)
 		} else {
 			DefaultCompleteWithFlags(c)(ctx)
@@ -263,16 +329,107 @@ func ShowCommandCompletions(ctx *Context, command string) {
 
 }
 
+// usageLine returns a one-line synopsis of how to invoke a command
+func usageLine(name string, hasFlags, hasCommands bool, argsUsageText string, choices *ChoiceArgs) string {
+	line := name
+	if hasFlags {
+		line += " [flags]"
+	}
+	if hasCommands {
+		line += " <command>"
+	}
+	if argsUsageText != "" {
+		line += " " + argsUsage(argsUsageText)
+	} else if choices != nil {
+		line += " " + choices.Usage()
+	} else if !hasCommands {
+		line += " [arguments...]"
+	}
+	return line
+}
+
+// printCompactUsageError writes a usage error along with the one-line usage
+func printCompactUsageError(w io.Writer, err error, usageLine string) {
+	_, _ = fmt.Fprintf(w, "Incorrect Usage: %s\nUsage: %s\n", err, usageLine)
+}
+
+// argsUsage renders an ArgsUsage with placeholders given in backticks as the
+// placeholders, like unquoteUsage does for flag usage, e.g. "the `FILE` to
+// load" renders as "FILE" and "`SRC` `DST`" as "SRC DST"
+func argsUsage(usage string) string {
+	parts := strings.Split(usage, "`")
+
+	var placeholders []string
+	for i := 1; i < len(parts)-1; i += 2 {
+		placeholders = append(placeholders, parts[i])
+	}
+	if len(placeholders) == 0 {
+		return usage
+	}
+	return strings.Join(placeholders, " ")
+}
+
+// HelpSectionPosition is where a HelpSection is shown in the default help
+type HelpSectionPosition string
+
+const (
+	// HelpSectionAfterUsage shows the section right after USAGE
+	HelpSectionAfterUsage HelpSectionPosition = "after-usage"
+	// HelpSectionAfterDescription shows the section right after DESCRIPTION
+	HelpSectionAfterDescription HelpSectionPosition = "after-description"
+	// HelpSectionAfterCommands shows the section right after COMMANDS
+	HelpSectionAfterCommands HelpSectionPosition = "after-commands"
+	// HelpSectionAfterFlags shows the section right after the options
+	HelpSectionAfterFlags HelpSectionPosition = "after-flags"
+)
+
+// HelpSection is an additional section of the default help, e.g. to document
+// environment variables without a custom help template
+type HelpSection struct {
+	// Title of the section, e.g. "ENVIRONMENT"
+	Title string
+	// Text of the section
+	Body string
+	// Function returning the text of the section, used instead of Body
+	// if set
+	BodyFunc func() string
+	// Where the section is shown, defaults to HelpSectionAfterFlags
+	Position HelpSectionPosition
+}
+
+// helpSections renders the sections to show at the given position
+func helpSections(sections []HelpSection, position HelpSectionPosition) string {
+	var b strings.Builder
+	for _, section := range sections {
+		pos := section.Position
+		if pos == "" {
+			pos = HelpSectionAfterFlags
+		}
+		if pos != position {
+			continue
+		}
+
+		body := section.Body
+		if section.BodyFunc != nil {
+			body = section.BodyFunc()
+		}
+		_, _ = fmt.Fprintf(&b, "\n\n%s:%s", section.Title, strings.TrimRight(nindent(3, strings.TrimSpace(body)), " "))
+	}
+	return b.String()
+}
+
 // printHelpCustom is the default implementation of HelpPrinterCustom.
 //
 // The customFuncs map will be combined with a default template.FuncMap to
 // allow using arbitrary functions in template rendering.
 func printHelpCustom(out io.Writer, templ string, data interface{}, customFuncs map[string]interface{}) {
 	funcMap := template.FuncMap{
-		"join":    strings.Join,
-		"indent":  indent,
-		"nindent": nindent,
-		"trim":    strings.TrimSpace,
+		"join":         strings.Join,
+		"indent":       indent,
+		"nindent":      nindent,
+		"trim":         strings.TrimSpace,
+		"argsUsage":    argsUsage,
+		"helpSections": helpSections,
 	}
 	for key, value := range customFuncs {
 		funcMap[key] = value
@@ -357,7 +514,7 @@ func checkCompletions(c *Context) bool {
 
 	if args := c.Args(); args.Present() {
 		name := args.First()
-		if cmd := c.App.Command(name); cmd != nil {
+		if cmd := c.App.lookupCommand(c, name); cmd != nil {
 			// let the command handle the completion
 			return false
 		}
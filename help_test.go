@@ -2,13 +2,16 @@ package cli
 
 import (
 	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 func Test_ShowAppHelp_NoAuthor(t *testing.T) {
@@ -535,6 +538,53 @@ func TestShowSubcommandHelp_SubcommandUsageText(t *testing.T) {
 	}
 }
 
+func TestShowAppHelp_HelpSections(t *testing.T) {
+	output := &bytes.Buffer{}
+	app := &App{
+		Name:   "app",
+		Writer: output,
+		HelpSections: []HelpSection{
+			{Title: "ENVIRONMENT", BodyFunc: func() string { return "APP_HOME  home directory" }},
+			{Title: "EXAMPLES", Body: "app --help", Position: HelpSectionAfterUsage},
+		},
+		Commands: []*Command{
+			{
+				Name:         "deploy",
+				Usage:        "deploy it",
+				HelpSections: []HelpSection{{Title: "ENVIRONMENT", Body: "TOKEN  API token"}},
+			},
+		},
+	}
+
+	_ = app.Run([]string{"app", "--help"})
+	expected := `USAGE:
+   app [global options] command [command options] [arguments...]
+
+EXAMPLES:
+   app --help
+
+COMMANDS:`
+	if !strings.Contains(output.String(), expected) {
+		t.Errorf("expected EXAMPLES after USAGE, got: %q", output.String())
+	}
+	if !strings.HasSuffix(output.String(), "show help (default: false)\n\nENVIRONMENT:\n   APP_HOME  home directory\n") {
+		t.Errorf("expected ENVIRONMENT after the options, got: %q", output.String())
+	}
+
+	output.Reset()
+	_ = app.Run([]string{"app", "deploy", "--help"})
+	if !strings.HasSuffix(output.String(), "show help (default: false)\n\nENVIRONMENT:\n   TOKEN  API token\n") {
+		t.Errorf("expected ENVIRONMENT after the command options, got: %q", output.String())
+	}
+
+	output.Reset()
+	app.Commands[0].HelpSections = nil
+	_ = app.Run([]string{"app", "deploy", "--help"})
+	if !strings.HasSuffix(output.String(), "show help (default: false)\n   \n") {
+		t.Errorf("expected the options to render as before without sections, got: %q", output.String())
+	}
+}
+
 func TestShowAppHelp_HiddenCommand(t *testing.T) {
 	app := &App{
 		Commands: []*Command{
@@ -923,3 +973,224 @@ func TestHideHelpCommand_WithSubcommands(t *testing.T) {
 		t.Errorf("Run returned unexpected error: %v", err)
 	}
 }
+
+func TestShowCommandHelp_ArgsUsagePlaceholder(t *testing.T) {
+	output := &bytes.Buffer{}
+	app := &App{
+		Name:   "foo",
+		Writer: output,
+		Commands: []*Command{
+			{
+				Name:      "load",
+				ArgsUsage: "the `FILE` to load",
+				Action:    func(c *Context) error { return nil },
+			},
+		},
+	}
+
+	_ = app.Run([]string{"foo", "help", "load"})
+
+	if !strings.Contains(output.String(), "foo load FILE\n") {
+		t.Errorf("expected the args usage placeholder in the usage line; got: %q", output.String())
+	}
+}
+
+func TestShowCommandHelp_ArgsUsageMultiplePlaceholders(t *testing.T) {
+	output := &bytes.Buffer{}
+	cmd := &Command{
+		Name:      "copy",
+		ArgsUsage: "`SRC` `DST`",
+		Action:    func(c *Context) error { return nil },
+	}
+	app := &App{
+		Name:     "foo",
+		Writer:   output,
+		Commands: []*Command{cmd},
+	}
+
+	_ = app.Run([]string{"foo", "help", "copy"})
+
+	if !strings.Contains(output.String(), "foo copy SRC DST\n") {
+		t.Errorf("expected every args usage placeholder in the usage line; got: %q", output.String())
+	}
+	expect(t, cmd.UsageLine(), "foo copy SRC DST")
+}
+
+func TestHideHelp_RootOnly(t *testing.T) {
+	output := &bytes.Buffer{}
+	app := &App{
+		Name:     "foo",
+		HideHelp: true,
+		Writer:   output,
+		Commands: []*Command{
+			{
+				Name:   "leaf",
+				Action: func(c *Context) error { return nil },
+			},
+			{
+				Name:        "parent",
+				Subcommands: []*Command{{Name: "child"}},
+			},
+		},
+	}
+
+	err := app.Run([]string{"foo", "--help"})
+	if err == nil {
+		t.Error("expected an error for the undefined help flag on the root")
+	}
+	if hasFlag(app.Flags, HelpFlag) {
+		t.Error("expected the root flags not to include the help flag")
+	}
+
+	output.Reset()
+	err = app.Run([]string{"foo", "leaf", "--help"})
+	expect(t, err, nil)
+	if !strings.Contains(output.String(), "foo leaf - ") {
+		t.Errorf("expected help for the leaf command, got: %q", output.String())
+	}
+	if !hasFlag(app.Commands[0].Flags, HelpFlag) {
+		t.Error("expected the leaf command to register the help flag")
+	}
+
+	output.Reset()
+	err = app.Run([]string{"foo", "parent", "--help"})
+	expect(t, err, nil)
+	if !strings.Contains(output.String(), "foo parent - ") {
+		t.Errorf("expected help for the parent command, got: %q", output.String())
+	}
+}
+
+func TestDefaultCompleteWithFlags_DefaultValue(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = []string{"foo", "deploy", "--region", "--generate-bash-completion"}
+
+	output := &bytes.Buffer{}
+	app := &App{
+		Name:                 "foo",
+		EnableBashCompletion: true,
+		Writer:               output,
+		Commands: []*Command{
+			{
+				Name: "deploy",
+				Flags: []Flag{
+					&StringFlag{Name: "region", Value: "eu-west-1"},
+					&StringFlag{Name: "token", Value: "s3cr3t", FlagOptions: FlagOptions{Sensitive: true}},
+				},
+			},
+		},
+	}
+
+	err := app.Run(os.Args)
+	expect(t, err, nil)
+	expect(t, output.String(), "eu-west-1\n")
+
+	output.Reset()
+	os.Args = []string{"foo", "deploy", "--token", "--generate-bash-completion"}
+	err = app.Run(os.Args)
+	expect(t, err, nil)
+	expect(t, output.String(), "")
+}
+
+func TestDefaultCompleteWithFlags_AssignedValue(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+
+	output := &bytes.Buffer{}
+	app := &App{
+		Name:                 "foo",
+		EnableBashCompletion: true,
+		Writer:               output,
+		Flags:                []Flag{&StringFlag{Name: "region", Value: "eu-west-1"}},
+	}
+
+	os.Args = []string{"foo", "--region=", "--generate-bash-completion"}
+	err := app.Run(os.Args)
+	expect(t, err, nil)
+	expect(t, output.String(), "--region=eu-west-1\n")
+
+	output.Reset()
+	os.Args = []string{"foo", "--region=us", "--generate-bash-completion"}
+	err = app.Run(os.Args)
+	expect(t, err, nil)
+	expect(t, output.String(), "")
+}
+
+// lineWriter passes each write on to a channel, or fails if err is set
+type lineWriter struct {
+	lines chan string
+	err   error
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.lines <- string(p)
+	return len(p), nil
+}
+
+func TestDefaultCompleteWithFlags_CompleteValues(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = []string{"foo", "--host", "--generate-bash-completion"}
+
+	writer := &lineWriter{lines: make(chan string, 1)}
+	var written []string
+	app := &App{
+		Name:                 "foo",
+		EnableBashCompletion: true,
+		Writer:               writer,
+		Flags: []Flag{
+			&StringFlag{
+				Name: "host",
+				CompleteValues: func(ctx *Context) <-chan string {
+					values := make(chan string)
+					go func() {
+						defer close(values)
+						for i := 0; i < 3; i++ {
+							values <- fmt.Sprintf("host%d", i)
+							// the next value is only produced once this one was written
+							select {
+							case line := <-writer.lines:
+								written = append(written, line)
+							case <-time.After(time.Second):
+								t.Errorf("value %d was not written before the next was produced", i)
+								return
+							}
+						}
+					}()
+					return values
+				},
+			},
+		},
+	}
+
+	err := app.Run(os.Args)
+	expect(t, err, nil)
+	expect(t, written, []string{"host0\n", "host1\n", "host2\n"})
+
+	// a failing writer stops completion and cancels the producer
+	cancelled := make(chan struct{})
+	writer.err = errors.New("broken pipe")
+	app.Flags[0].(*StringFlag).CompleteValues = func(ctx *Context) <-chan string {
+		values := make(chan string)
+		go func() {
+			defer close(values)
+			for {
+				select {
+				case values <- "host":
+				case <-ctx.Done():
+					close(cancelled)
+					return
+				}
+			}
+		}()
+		return values
+	}
+
+	err = app.Run(os.Args)
+	expect(t, err, nil)
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("expected the producer to be cancelled")
+	}
+}
@@ -1,35 +1,47 @@
 package cli
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"strings"
 )
 
 type iterativeParser interface {
 	newFlagSet() (*flag.FlagSet, error)
 	useShortOptionHandling() bool
+	shortFlagResolver() ShortFlagResolverFunc
+	errorOnDuplicateScalarFlag() bool
+	flagsToParse() []Flag
 }
 
 // To enable short-option handling (e.g., "-it" vs "-i -t") we have to
 // iteratively catch parsing errors. This way we achieve LR parsing without
 // transforming any arguments. Otherwise, there is no way we can discriminate
 // combined short options from common arguments that should be left untouched.
+// An argument naming an existing flag exactly (e.g. "-it" when there is a flag
+// named "it") is always taken as that flag; only otherwise is it split, either
+// by the ShortFlagResolverFunc or by the longest defined flag names.
 // Pass `shellComplete` to continue parsing options on failure during shell
 // completion when, the user-supplied options may be incomplete.
-func parseIter(set *flag.FlagSet, ip iterativeParser, args []string, shellComplete bool) error {
+// The returned map holds how many times each flag name was given.
+func parseIter(set *flag.FlagSet, ip iterativeParser, args []string, shellComplete bool) (map[string]int, error) {
 	for {
-		err := set.Parse(args)
+		occurrences, err := parseArgs(set, args, ip.flagsToParse())
+		if err == nil && ip.errorOnDuplicateScalarFlag() {
+			err = checkDuplicateScalarFlags(set, ip.flagsToParse(), occurrences)
+		}
 		if !ip.useShortOptionHandling() || err == nil {
 			if shellComplete {
-				return nil
+				return occurrences, nil
 			}
-			return err
+			return occurrences, err
 		}
 
 		errStr := err.Error()
 		trimmed := strings.TrimPrefix(errStr, "flag provided but not defined: -")
 		if errStr == trimmed {
-			return err
+			return occurrences, err
 		}
 
 		// regenerate the initial args with the split short opts
@@ -41,9 +53,9 @@ func parseIter(set *flag.FlagSet, ip iterativeParser, args []string, shellComple
 			}
 
 			// if we can't split, the error was accurate
-			shortOpts := splitShortOptions(set, arg)
+			shortOpts := splitShortOptions(set, arg, ip.shortFlagResolver())
 			if len(shortOpts) == 1 {
-				return err
+				return occurrences, err
 			}
 
 			// swap current argument with the split version
@@ -55,35 +67,228 @@ func parseIter(set *flag.FlagSet, ip iterativeParser, args []string, shellComple
 		// This should be an impossible to reach code path, but in case the arg
 		// splitting failed to happen, this will prevent infinite loops
 		if !argsWereSplit {
-			return err
+			return occurrences, err
 		}
 
 		// Since custom parsing failed, replace the flag set before retrying
 		newSet, err := ip.newFlagSet()
 		if err != nil {
-			return err
+			return occurrences, err
 		}
 		*set = *newSet
 	}
 }
 
-func splitShortOptions(set *flag.FlagSet, arg string) []string {
-	shortFlagsExist := func(s string) bool {
-		for _, c := range s[1:] {
-			if f := set.Lookup(string(c)); f == nil {
-				return false
+// parsingValue wraps a flag.Value while parsing to count how many times the
+// flag is given on the command line and to let it recover from parse errors
+type parsingValue struct {
+	flag.Value
+	name         string
+	occurrences  map[string]int
+	onParseError ParseErrorFunc
+	// whether values from the command line are ignored, as the value from the
+	// environment takes precedence
+	envOverrides bool
+}
+
+func (p *parsingValue) Set(value string) error {
+	p.occurrences[p.name]++
+	if p.envOverrides {
+		return nil
+	}
+
+	err := p.Value.Set(value)
+	if err == nil || p.onParseError == nil {
+		return err
+	}
+
+	recovered, handled := p.onParseError(value, err)
+	if !handled {
+		if recovered != "" {
+			return errors.New(recovered)
+		}
+		return err
+	}
+	return p.Value.Set(recovered)
+}
+
+func (p *parsingValue) String() string {
+	// flag.FlagSet calls String on a zero value when printing defaults
+	if p.Value == nil {
+		return ""
+	}
+	return p.Value.String()
+}
+
+// IsBoolFlag lets boolean flags be given without a value while wrapped
+func (p *parsingValue) IsBoolFlag() bool {
+	bf, ok := p.Value.(interface{ IsBoolFlag() bool })
+	return ok && bf.IsBoolFlag()
+}
+
+// parseArgs parses args into set, returning how many times each flag name was
+// given. The OnParseError functions of flags are used to recover from invalid
+// values.
+func parseArgs(set *flag.FlagSet, args []string, flags []Flag) (map[string]int, error) {
+	onParseErrors := make(map[string]ParseErrorFunc)
+	envOverrides := make(map[string]bool)
+	for _, f := range flags {
+		if fn := flagOptions(f).OnParseError; fn != nil {
+			for _, name := range f.Names() {
+				onParseErrors[name] = fn
+			}
+		}
+		// look the env up again rather than trusting IsSet, which stays
+		// true once a previous run of the App found the env var set
+		if !flagOptions(f).EnvOverridesFlag {
+			continue
+		}
+		if val, _, ok := flagEnvLookup(f); ok && val != "" {
+			for _, name := range f.Names() {
+				envOverrides[name] = true
+			}
+		}
+	}
+
+	occurrences := make(map[string]int)
+	set.VisitAll(func(f *flag.Flag) {
+		f.Value = &parsingValue{
+			Value:        f.Value,
+			name:         f.Name,
+			occurrences:  occurrences,
+			onParseError: onParseErrors[f.Name],
+			envOverrides: envOverrides[f.Name],
+		}
+	})
+
+	err := set.Parse(args)
+
+	set.VisitAll(func(f *flag.Flag) {
+		f.Value = f.Value.(*parsingValue).Value
+	})
+	if err == nil {
+		err = applyEnvOnlyFlags(set, flags)
+	}
+	return occurrences, err
+}
+
+// argsTerminated returns true if parsing args into set stopped at a "--"
+// rather than at the first argument which is not a flag
+func argsTerminated(set *flag.FlagSet, args []string) bool {
+	i := len(args) - set.NArg() - 1
+	if i < 0 || args[i] != "--" {
+		return false
+	}
+
+	// make sure the "--" is not the value of the flag before it
+	if i > 0 && strings.HasPrefix(args[i-1], "-") && !strings.Contains(args[i-1], "=") {
+		if f := set.Lookup(strings.TrimLeft(args[i-1], "-")); f != nil {
+			bf, ok := f.Value.(interface{ IsBoolFlag() bool })
+			return ok && bf.IsBoolFlag()
+		}
+	}
+	return true
+}
+
+// applyEnvOnlyFlags applies the flags which may only be set through the
+// environment to set once it is parsed, so that they can be looked up like any
+// other flag but giving them on the command line is an undefined flag error
+func applyEnvOnlyFlags(set *flag.FlagSet, flags []Flag) error {
+	for _, f := range flags {
+		if !flagOptions(f).EnvOnly {
+			continue
+		}
+		if err := f.Apply(set); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDuplicateScalarFlags returns an error if a flag which takes a single
+// value was given more than once, under any of its names
+func checkDuplicateScalarFlags(set *flag.FlagSet, flags []Flag, occurrences map[string]int) error {
+	for _, f := range flags {
+		names := f.Names()
+		if len(names) == 0 {
+			continue
+		}
+
+		count, repeated := 0, names[0]
+		for _, name := range names {
+			count += occurrences[name]
+			if occurrences[name] > occurrences[repeated] {
+				repeated = name
 			}
 		}
-		return true
+		if count < 2 {
+			continue
+		}
+
+		if ff := set.Lookup(repeated); ff != nil && !takesSingleValue(ff.Value) {
+			continue
+		}
+		return fmt.Errorf("flag %s%s specified multiple times", prefixFor(repeated), repeated)
+	}
+	return nil
+}
+
+// takesSingleValue returns false for values which are given without one, like
+// those of bool flags, and for values which implement RepeatableValue
+func takesSingleValue(value flag.Value) bool {
+	if bf, ok := value.(interface{ IsBoolFlag() bool }); ok && bf.IsBoolFlag() {
+		return false
+	}
+	if rv, ok := value.(RepeatableValue); ok && rv.IsRepeatable() {
+		return false
 	}
+	return true
+}
 
-	if !isSplittable(arg) || !shortFlagsExist(arg) {
+// splitShortOptions splits a combined short option into the flags it stands
+// for, taking the longest defined flag name at each position, so that with
+// the flags "ab" and "c" -abc is split into -ab -c
+func splitShortOptions(set *flag.FlagSet, arg string, resolve ShortFlagResolverFunc) []string {
+	if !isSplittable(arg) {
 		return []string{arg}
 	}
 
-	separated := make([]string, 0, len(arg)-1)
-	for _, flagChar := range arg[1:] {
-		separated = append(separated, "-"+string(flagChar))
+	if resolve != nil {
+		if names, ok := resolve(arg[1:]); ok {
+			return resolvedShortOptions(set, arg, names)
+		}
+	}
+
+	var separated []string
+	remaining := []rune(arg[1:])
+	for len(remaining) > 0 {
+		n := len(remaining)
+		for ; n > 0; n-- {
+			if f := set.Lookup(string(remaining[:n])); f != nil {
+				break
+			}
+		}
+		if n == 0 {
+			return []string{arg}
+		}
+
+		separated = append(separated, "-"+string(remaining[:n]))
+		remaining = remaining[n:]
+	}
+
+	return separated
+}
+
+// resolvedShortOptions turns the flag names returned by a
+// ShortFlagResolverFunc into arguments, leaving the argument untouched when
+// any of the names is unknown so that the parsing error stays accurate
+func resolvedShortOptions(set *flag.FlagSet, arg string, names []string) []string {
+	separated := make([]string, 0, len(names))
+	for _, name := range names {
+		if f := set.Lookup(name); f == nil {
+			return []string{arg}
+		}
+		separated = append(separated, "-"+name)
 	}
 
 	return separated
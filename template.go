@@ -7,13 +7,13 @@ var AppHelpTemplate = `NAME:
    {{.Name}}{{if .Usage}} - {{.Usage}}{{end}}
 
 USAGE:
-   {{if .UsageText}}{{.UsageText}}{{else}}{{.HelpName}} {{if .VisibleFlags}}[global options]{{end}}{{if .Commands}} command [command options]{{end}} {{if .ArgsUsage}}{{.ArgsUsage}}{{else}}[arguments...]{{end}}{{end}}{{if .Version}}{{if not .HideVersion}}
+   {{if .UsageText}}{{.UsageText}}{{else}}{{.HelpName}} {{if .VisibleFlags}}[global options]{{end}}{{if .Commands}} command [command options]{{end}} {{if .ArgsUsage}}{{argsUsage .ArgsUsage}}{{else if .ChoiceArgs}}{{.ChoiceArgs.Usage}}{{else}}[arguments...]{{end}}{{end}}{{helpSections .HelpSections "after-usage"}}{{if .Version}}{{if not .HideVersion}}
 
 VERSION:
    {{.Version}}{{end}}{{end}}{{if .Description}}
 
 DESCRIPTION:
-   {{.Description | nindent 3 | trim}}{{end}}{{if len .Authors}}
+   {{.Description | nindent 3 | trim}}{{end}}{{helpSections .HelpSections "after-description"}}{{if len .Authors}}
 
 AUTHOR{{with $length := len .Authors}}{{if ne 1 $length}}S{{end}}{{end}}:
    {{range $index, $author := .Authors}}{{if $index}}
@@ -21,12 +21,12 @@ AUTHOR{{with $length := len .Authors}}{{if ne 1 $length}}S{{end}}{{end}}:
 
 COMMANDS:{{range .VisibleCategories}}{{if .Name}}
    {{.Name}}:{{range .VisibleCommands}}
-     {{join .Names ", "}}{{"\t"}}{{.Usage}}{{end}}{{else}}{{range .VisibleCommands}}
-   {{join .Names ", "}}{{"\t"}}{{.Usage}}{{end}}{{end}}{{end}}{{end}}{{if .VisibleFlags}}
+     {{join .Names ", "}}{{"\t"}}{{.Usage}}{{if .Deprecated}} (deprecated){{end}}{{end}}{{else}}{{range .VisibleCommands}}
+   {{join .Names ", "}}{{"\t"}}{{.Usage}}{{if .Deprecated}} (deprecated){{end}}{{end}}{{end}}{{end}}{{end}}{{helpSections .HelpSections "after-commands"}}{{if .VisibleFlags}}
 
 GLOBAL OPTIONS:
    {{range $index, $option := .VisibleFlags}}{{if $index}}
-   {{end}}{{$option}}{{end}}{{end}}{{if .Copyright}}
+   {{end}}{{$option}}{{end}}{{end}}{{helpSections .HelpSections "after-flags"}}{{if .Copyright}}
 
 COPYRIGHT:
    {{.Copyright}}{{end}}
@@ -36,20 +36,21 @@ COPYRIGHT:
 // cli.go uses text/template to render templates. You can
 // render custom help text by setting this variable.
 var CommandHelpTemplate = `NAME:
-   {{.HelpName}} - {{.Usage}}
+   {{.HelpName}} - {{.Usage}}{{if .Deprecated}} (deprecated){{end}}
 
 USAGE:
-   {{if .UsageText}}{{.UsageText}}{{else}}{{.HelpName}}{{if .VisibleFlags}} [command options]{{end}} {{if .ArgsUsage}}{{.ArgsUsage}}{{else}}[arguments...]{{end}}{{end}}{{if .Category}}
+   {{if .UsageText}}{{.UsageText}}{{else}}{{.HelpName}}{{if .VisibleFlags}} [command options]{{end}} {{if .ArgsUsage}}{{argsUsage .ArgsUsage}}{{else if .ChoiceArgs}}{{.ChoiceArgs.Usage}}{{else}}[arguments...]{{end}}{{end}}{{helpSections .HelpSections "after-usage"}}{{if .Category}}
 
 CATEGORY:
    {{.Category}}{{end}}{{if .Description}}
 
 DESCRIPTION:
-   {{.Description | nindent 3 | trim}}{{end}}{{if .VisibleFlags}}
+   {{.Description | nindent 3 | trim}}{{end}}{{helpSections .HelpSections "after-description"}}{{helpSections .HelpSections "after-commands"}}{{$afterFlags := helpSections .HelpSections "after-flags"}}{{if .VisibleFlags}}
 
 OPTIONS:
-   {{range .VisibleFlags}}{{.}}
-   {{end}}{{end}}
+   {{range $index, $option := .VisibleFlags}}{{if $index}}
+   {{end}}{{$option}}{{end}}{{if not $afterFlags}}
+   {{end}}{{end}}{{$afterFlags}}
 `
 
 // SubcommandHelpTemplate is the text template for the subcommand help topic.
@@ -59,19 +60,20 @@ var SubcommandHelpTemplate = `NAME:
    {{.HelpName}} - {{.Usage}}
 
 USAGE:
-   {{if .UsageText}}{{.UsageText}}{{else}}{{.HelpName}} command{{if .VisibleFlags}} [command options]{{end}} {{if .ArgsUsage}}{{.ArgsUsage}}{{else}}[arguments...]{{end}}{{end}}{{if .Description}}
+   {{if .UsageText}}{{.UsageText}}{{else}}{{.HelpName}} command{{if .VisibleFlags}} [command options]{{end}} {{if .ArgsUsage}}{{argsUsage .ArgsUsage}}{{else if .ChoiceArgs}}{{.ChoiceArgs.Usage}}{{else}}[arguments...]{{end}}{{end}}{{helpSections .HelpSections "after-usage"}}{{if .Description}}
 
 DESCRIPTION:
-   {{.Description | nindent 3 | trim}}{{end}}
+   {{.Description | nindent 3 | trim}}{{end}}{{helpSections .HelpSections "after-description"}}
 
 COMMANDS:{{range .VisibleCategories}}{{if .Name}}
    {{.Name}}:{{range .VisibleCommands}}
-     {{join .Names ", "}}{{"\t"}}{{.Usage}}{{end}}{{else}}{{range .VisibleCommands}}
-   {{join .Names ", "}}{{"\t"}}{{.Usage}}{{end}}{{end}}{{end}}{{if .VisibleFlags}}
+     {{join .Names ", "}}{{"\t"}}{{.Usage}}{{if .Deprecated}} (deprecated){{end}}{{end}}{{else}}{{range .VisibleCommands}}
+   {{join .Names ", "}}{{"\t"}}{{.Usage}}{{if .Deprecated}} (deprecated){{end}}{{end}}{{end}}{{end}}{{helpSections .HelpSections "after-commands"}}{{$afterFlags := helpSections .HelpSections "after-flags"}}{{if .VisibleFlags}}
 
 OPTIONS:
-   {{range .VisibleFlags}}{{.}}
-   {{end}}{{end}}
+   {{range $index, $option := .VisibleFlags}}{{if $index}}
+   {{end}}{{$option}}{{end}}{{if not $afterFlags}}
+   {{end}}{{end}}{{$afterFlags}}
 `
 
 var MarkdownDocTemplate = `% {{ .App.Name }} {{ .SectionNum }}
@@ -118,3 +120,70 @@ end
 
 {{ range $v := .Completions }}{{ $v }}
 {{ end }}`
+
+// BashCompletionTemplate is the text template used by ToBashCompletion. It
+// is given the Name of the program to complete and a FuncName made from it.
+// Generic is set to render autocomplete/bash_autocomplete, which completes
+// the program named by $PROG.
+var BashCompletionTemplate = `#! /bin/bash
+{{ if .Generic }}
+: ${PROG:=$(basename ${BASH_SOURCE})}
+{{ else }}# {{ .Name }} bash completion
+{{ end }}
+_{{ .FuncName }}_bash_autocomplete() {
+  if [[ "${COMP_WORDS[0]}" != "source" ]]; then
+    local cur opts base
+    COMPREPLY=()
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    # bash splits "--flag=value" into several words if "=" is a word break
+    local line="${COMP_LINE:0:$COMP_POINT}"
+    local word="${line##* }"
+    if [[ "$word" == "-"*"="* ]]; then
+      opts=$( ${line% *} ${word} --generate-bash-completion )
+      COMPREPLY=( $(compgen -W "${opts}" -- ${word}) )
+      if [[ "$cur" != "$word" ]]; then
+        COMPREPLY=( "${COMPREPLY[@]#*=}" )
+      fi
+      return 0
+    fi
+    if [[ "$cur" == "-"* ]]; then
+      opts=$( ${COMP_WORDS[@]:0:$COMP_CWORD} ${cur} --generate-bash-completion )
+    else
+      opts=$( ${COMP_WORDS[@]:0:$COMP_CWORD} --generate-bash-completion )
+    fi
+    COMPREPLY=( $(compgen -W "${opts}" -- ${cur}) )
+    return 0
+  fi
+}
+
+complete -o bashdefault -o default -o nospace -F _{{ .FuncName }}_bash_autocomplete {{ .Name }}
+{{ if .Generic }}unset PROG
+{{ end }}`
+
+// ZshCompletionTemplate is the text template used by ToZshCompletion. It is
+// given the same values as BashCompletionTemplate, autocomplete/zsh_autocomplete
+// is rendered with $PROG as the Name.
+var ZshCompletionTemplate = `#compdef {{ .Name }}
+
+_{{ .FuncName }}_zsh_autocomplete() {
+
+  local -a opts
+  local cur
+  cur=${words[-1]}
+  if [[ "$cur" == "-"* ]]; then
+    opts=("${(@f)$(_CLI_ZSH_AUTOCOMPLETE_HACK=1 ${words[@]:0:#words[@]-1} ${cur} --generate-bash-completion)}")
+  else
+    opts=("${(@f)$(_CLI_ZSH_AUTOCOMPLETE_HACK=1 ${words[@]:0:#words[@]-1} --generate-bash-completion)}")
+  fi
+
+  if [[ "${opts[1]}" != "" ]]; then
+    _describe 'values' opts
+  else
+    _files
+  fi
+
+  return
+}
+
+compdef _{{ .FuncName }}_zsh_autocomplete {{ .Name }}
+`